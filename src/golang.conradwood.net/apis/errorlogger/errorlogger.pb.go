@@ -12,6 +12,22 @@ It has these top-level messages:
 	ProtoLog
 	ErrorLogRequest
 	ReadLogRequest
+	LogBatchRequest
+	LogAck
+	LogBatchResponse
+	ErrorGroup
+	SetGroupStateRequest
+	ListErrorGroupsRequest
+	ErrorGroupList
+	StatsRequest
+	StatsEntry
+	Alert
+	AlertRuleState
+	AlertList
+	Anomaly
+	GetAnomaliesRequest
+	AnomalyList
+	StatsResponse
 */
 package errorlogger
 
@@ -38,10 +54,96 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type GroupState int32
+
+const (
+	GroupState_OPEN         GroupState = 0
+	GroupState_ACKNOWLEDGED GroupState = 1
+	GroupState_RESOLVED     GroupState = 2
+	GroupState_MUTED        GroupState = 3
+	GroupState_REGRESSED    GroupState = 4
+)
+
+var GroupState_name = map[int32]string{
+	0: "OPEN",
+	1: "ACKNOWLEDGED",
+	2: "RESOLVED",
+	3: "MUTED",
+	4: "REGRESSED",
+}
+var GroupState_value = map[string]int32{
+	"OPEN":         0,
+	"ACKNOWLEDGED": 1,
+	"RESOLVED":     2,
+	"MUTED":        3,
+	"REGRESSED":    4,
+}
+
+func (x GroupState) String() string {
+	return proto.EnumName(GroupState_name, int32(x))
+}
+func (GroupState) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
+
+type GroupOrder int32
+
+const (
+	GroupOrder_BY_LAST_SEEN GroupOrder = 0
+	GroupOrder_BY_COUNT     GroupOrder = 1
+)
+
+var GroupOrder_name = map[int32]string{
+	0: "BY_LAST_SEEN",
+	1: "BY_COUNT",
+}
+var GroupOrder_value = map[string]int32{
+	"BY_LAST_SEEN": 0,
+	"BY_COUNT":     1,
+}
+
+func (x GroupOrder) String() string {
+	return proto.EnumName(GroupOrder_name, int32(x))
+}
+func (GroupOrder) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+type StatsField int32
+
+const (
+	StatsField_SERVICE         StatsField = 0
+	StatsField_METHOD          StatsField = 1
+	StatsField_CODE            StatsField = 2
+	StatsField_USER            StatsField = 3
+	StatsField_CALLING_SERVICE StatsField = 4
+	StatsField_TEAM            StatsField = 5
+)
+
+var StatsField_name = map[int32]string{
+	0: "SERVICE",
+	1: "METHOD",
+	2: "CODE",
+	3: "USER",
+	4: "CALLING_SERVICE",
+	5: "TEAM",
+}
+var StatsField_value = map[string]int32{
+	"SERVICE":         0,
+	"METHOD":          1,
+	"CODE":            2,
+	"USER":            3,
+	"CALLING_SERVICE": 4,
+	"TEAM":            5,
+}
+
+func (x StatsField) String() string {
+	return proto.EnumName(StatsField_name, int32(x))
+}
+func (StatsField) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
 type ProtoLog struct {
-	Err     *ErrorLogRequest `protobuf:"bytes,1,opt,name=Err" json:"Err,omitempty"`
-	User    *auth.User       `protobuf:"bytes,2,opt,name=User" json:"User,omitempty"`
-	Service *auth.User       `protobuf:"bytes,3,opt,name=Service" json:"Service,omitempty"`
+	Err      *ErrorLogRequest `protobuf:"bytes,1,opt,name=Err" json:"Err,omitempty"`
+	User     *auth.User       `protobuf:"bytes,2,opt,name=User" json:"User,omitempty"`
+	Service  *auth.User       `protobuf:"bytes,3,opt,name=Service" json:"Service,omitempty"`
+	Received uint32           `protobuf:"varint,4,opt,name=Received" json:"Received,omitempty"`
+	Team     string           `protobuf:"bytes,5,opt,name=Team" json:"Team,omitempty"`
 }
 
 func (m *ProtoLog) Reset()                    { *m = ProtoLog{} }
@@ -49,135 +151,938 @@ func (m *ProtoLog) String() string            { return proto.CompactTextString(m
 func (*ProtoLog) ProtoMessage()               {}
 func (*ProtoLog) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{0} }
 
-func (m *ProtoLog) GetErr() *ErrorLogRequest {
+func (m *ProtoLog) GetErr() *ErrorLogRequest {
+	if m != nil {
+		return m.Err
+	}
+	return nil
+}
+
+func (m *ProtoLog) GetUser() *auth.User {
+	if m != nil {
+		return m.User
+	}
+	return nil
+}
+
+func (m *ProtoLog) GetService() *auth.User {
+	if m != nil {
+		return m.Service
+	}
+	return nil
+}
+
+func (m *ProtoLog) GetReceived() uint32 {
+	if m != nil {
+		return m.Received
+	}
+	return 0
+}
+
+func (m *ProtoLog) GetTeam() string {
+	if m != nil {
+		return m.Team
+	}
+	return ""
+}
+
+type ErrorLogRequest struct {
+	UserID         string                   `protobuf:"bytes,1,opt,name=UserID" json:"UserID,omitempty"`
+	ServiceName    string                   `protobuf:"bytes,2,opt,name=ServiceName" json:"ServiceName,omitempty"`
+	MethodName     string                   `protobuf:"bytes,3,opt,name=MethodName" json:"MethodName,omitempty"`
+	Timestamp      uint32                   `protobuf:"varint,4,opt,name=Timestamp" json:"Timestamp,omitempty"`
+	ErrorCode      uint32                   `protobuf:"varint,5,opt,name=ErrorCode" json:"ErrorCode,omitempty"`
+	ErrorMessage   string                   `protobuf:"bytes,6,opt,name=ErrorMessage" json:"ErrorMessage,omitempty"`
+	LogMessage     string                   `protobuf:"bytes,7,opt,name=LogMessage" json:"LogMessage,omitempty"`
+	RequestID      string                   `protobuf:"bytes,9,opt,name=RequestID" json:"RequestID,omitempty"`
+	CallingService *auth.User               `protobuf:"bytes,10,opt,name=CallingService" json:"CallingService,omitempty"`
+	Errors         *goeasyops.GRPCErrorList `protobuf:"bytes,11,opt,name=Errors" json:"Errors,omitempty"`
+	IdempotencyKey string                   `protobuf:"bytes,12,opt,name=IdempotencyKey" json:"IdempotencyKey,omitempty"`
+}
+
+func (m *ErrorLogRequest) Reset()                    { *m = ErrorLogRequest{} }
+func (m *ErrorLogRequest) String() string            { return proto.CompactTextString(m) }
+func (*ErrorLogRequest) ProtoMessage()               {}
+func (*ErrorLogRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *ErrorLogRequest) GetUserID() string {
+	if m != nil {
+		return m.UserID
+	}
+	return ""
+}
+
+func (m *ErrorLogRequest) GetServiceName() string {
+	if m != nil {
+		return m.ServiceName
+	}
+	return ""
+}
+
+func (m *ErrorLogRequest) GetMethodName() string {
+	if m != nil {
+		return m.MethodName
+	}
+	return ""
+}
+
+func (m *ErrorLogRequest) GetTimestamp() uint32 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *ErrorLogRequest) GetErrorCode() uint32 {
+	if m != nil {
+		return m.ErrorCode
+	}
+	return 0
+}
+
+func (m *ErrorLogRequest) GetErrorMessage() string {
+	if m != nil {
+		return m.ErrorMessage
+	}
+	return ""
+}
+
+func (m *ErrorLogRequest) GetLogMessage() string {
+	if m != nil {
+		return m.LogMessage
+	}
+	return ""
+}
+
+func (m *ErrorLogRequest) GetRequestID() string {
+	if m != nil {
+		return m.RequestID
+	}
+	return ""
+}
+
+func (m *ErrorLogRequest) GetCallingService() *auth.User {
+	if m != nil {
+		return m.CallingService
+	}
+	return nil
+}
+
+func (m *ErrorLogRequest) GetErrors() *goeasyops.GRPCErrorList {
+	if m != nil {
+		return m.Errors
+	}
+	return nil
+}
+
+func (m *ErrorLogRequest) GetIdempotencyKey() string {
+	if m != nil {
+		return m.IdempotencyKey
+	}
+	return ""
+}
+
+type ReadLogRequest struct {
+	LogsToSend      uint32   `protobuf:"varint,1,opt,name=LogsToSend" json:"LogsToSend,omitempty"`
+	Services        []string `protobuf:"bytes,2,rep,name=Services" json:"Services,omitempty"`
+	UserIDs         []string `protobuf:"bytes,3,rep,name=UserIDs" json:"UserIDs,omitempty"`
+	Methods         []string `protobuf:"bytes,4,rep,name=Methods" json:"Methods,omitempty"`
+	ErrorCodes      []uint32 `protobuf:"varint,5,rep,packed,name=ErrorCodes" json:"ErrorCodes,omitempty"`
+	CallingServices []string `protobuf:"bytes,6,rep,name=CallingServices" json:"CallingServices,omitempty"`
+	RequestID       string   `protobuf:"bytes,7,opt,name=RequestID" json:"RequestID,omitempty"`
+	Text            string   `protobuf:"bytes,8,opt,name=Text" json:"Text,omitempty"`
+	StartTimestamp  uint32   `protobuf:"varint,9,opt,name=StartTimestamp" json:"StartTimestamp,omitempty"`
+	EndTimestamp    uint32   `protobuf:"varint,10,opt,name=EndTimestamp" json:"EndTimestamp,omitempty"`
+	IncludeMuted    bool     `protobuf:"varint,11,opt,name=IncludeMuted" json:"IncludeMuted,omitempty"`
+	Teams           []string `protobuf:"bytes,12,rep,name=Teams" json:"Teams,omitempty"`
+}
+
+func (m *ReadLogRequest) Reset()                    { *m = ReadLogRequest{} }
+func (m *ReadLogRequest) String() string            { return proto.CompactTextString(m) }
+func (*ReadLogRequest) ProtoMessage()               {}
+func (*ReadLogRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *ReadLogRequest) GetLogsToSend() uint32 {
+	if m != nil {
+		return m.LogsToSend
+	}
+	return 0
+}
+
+func (m *ReadLogRequest) GetServices() []string {
+	if m != nil {
+		return m.Services
+	}
+	return nil
+}
+
+func (m *ReadLogRequest) GetUserIDs() []string {
+	if m != nil {
+		return m.UserIDs
+	}
+	return nil
+}
+
+func (m *ReadLogRequest) GetMethods() []string {
+	if m != nil {
+		return m.Methods
+	}
+	return nil
+}
+
+func (m *ReadLogRequest) GetErrorCodes() []uint32 {
+	if m != nil {
+		return m.ErrorCodes
+	}
+	return nil
+}
+
+func (m *ReadLogRequest) GetCallingServices() []string {
+	if m != nil {
+		return m.CallingServices
+	}
+	return nil
+}
+
+func (m *ReadLogRequest) GetRequestID() string {
+	if m != nil {
+		return m.RequestID
+	}
+	return ""
+}
+
+func (m *ReadLogRequest) GetText() string {
+	if m != nil {
+		return m.Text
+	}
+	return ""
+}
+
+func (m *ReadLogRequest) GetStartTimestamp() uint32 {
+	if m != nil {
+		return m.StartTimestamp
+	}
+	return 0
+}
+
+func (m *ReadLogRequest) GetEndTimestamp() uint32 {
+	if m != nil {
+		return m.EndTimestamp
+	}
+	return 0
+}
+
+func (m *ReadLogRequest) GetIncludeMuted() bool {
+	if m != nil {
+		return m.IncludeMuted
+	}
+	return false
+}
+
+func (m *ReadLogRequest) GetTeams() []string {
+	if m != nil {
+		return m.Teams
+	}
+	return nil
+}
+
+type LogBatchRequest struct {
+	Logs []*ErrorLogRequest `protobuf:"bytes,1,rep,name=Logs" json:"Logs,omitempty"`
+}
+
+func (m *LogBatchRequest) Reset()                    { *m = LogBatchRequest{} }
+func (m *LogBatchRequest) String() string            { return proto.CompactTextString(m) }
+func (*LogBatchRequest) ProtoMessage()               {}
+func (*LogBatchRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *LogBatchRequest) GetLogs() []*ErrorLogRequest {
+	if m != nil {
+		return m.Logs
+	}
+	return nil
+}
+
+// the result for one entry of a LogBatch() or LogStream() call
+type LogAck struct {
+	Index    uint32 `protobuf:"varint,1,opt,name=Index" json:"Index,omitempty"`
+	Accepted bool   `protobuf:"varint,2,opt,name=Accepted" json:"Accepted,omitempty"`
+	Error    string `protobuf:"bytes,3,opt,name=Error" json:"Error,omitempty"`
+}
+
+func (m *LogAck) Reset()                    { *m = LogAck{} }
+func (m *LogAck) String() string            { return proto.CompactTextString(m) }
+func (*LogAck) ProtoMessage()               {}
+func (*LogAck) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *LogAck) GetIndex() uint32 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *LogAck) GetAccepted() bool {
+	if m != nil {
+		return m.Accepted
+	}
+	return false
+}
+
+func (m *LogAck) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+type LogBatchResponse struct {
+	Acks []*LogAck `protobuf:"bytes,1,rep,name=Acks" json:"Acks,omitempty"`
+}
+
+func (m *LogBatchResponse) Reset()                    { *m = LogBatchResponse{} }
+func (m *LogBatchResponse) String() string            { return proto.CompactTextString(m) }
+func (*LogBatchResponse) ProtoMessage()               {}
+func (*LogBatchResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *LogBatchResponse) GetAcks() []*LogAck {
+	if m != nil {
+		return m.Acks
+	}
+	return nil
+}
+
+// errors which are the same problem: same service, method, code and LogMessage (ignoring numbers, IDs and UUIDs)
+type ErrorGroup struct {
+	Fingerprint     string     `protobuf:"bytes,1,opt,name=Fingerprint" json:"Fingerprint,omitempty"`
+	ServiceName     string     `protobuf:"bytes,2,opt,name=ServiceName" json:"ServiceName,omitempty"`
+	MethodName      string     `protobuf:"bytes,3,opt,name=MethodName" json:"MethodName,omitempty"`
+	ErrorCode       uint32     `protobuf:"varint,4,opt,name=ErrorCode" json:"ErrorCode,omitempty"`
+	Message         string     `protobuf:"bytes,5,opt,name=Message" json:"Message,omitempty"`
+	FirstSeen       uint32     `protobuf:"varint,6,opt,name=FirstSeen" json:"FirstSeen,omitempty"`
+	LastSeen        uint32     `protobuf:"varint,7,opt,name=LastSeen" json:"LastSeen,omitempty"`
+	Count           uint64     `protobuf:"varint,8,opt,name=Count" json:"Count,omitempty"`
+	UserIDs         []string   `protobuf:"bytes,9,rep,name=UserIDs" json:"UserIDs,omitempty"`
+	Sample          *ProtoLog  `protobuf:"bytes,10,opt,name=Sample" json:"Sample,omitempty"`
+	State           GroupState `protobuf:"varint,11,opt,name=State,enum=errorlogger.GroupState" json:"State,omitempty"`
+	StateChanged    uint32     `protobuf:"varint,12,opt,name=StateChanged" json:"StateChanged,omitempty"`
+	MutedUntil      uint32     `protobuf:"varint,13,opt,name=MutedUntil" json:"MutedUntil,omitempty"`
+	MutedUntilCount uint64     `protobuf:"varint,14,opt,name=MutedUntilCount" json:"MutedUntilCount,omitempty"`
+}
+
+func (m *ErrorGroup) Reset()                    { *m = ErrorGroup{} }
+func (m *ErrorGroup) String() string            { return proto.CompactTextString(m) }
+func (*ErrorGroup) ProtoMessage()               {}
+func (*ErrorGroup) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *ErrorGroup) GetFingerprint() string {
+	if m != nil {
+		return m.Fingerprint
+	}
+	return ""
+}
+
+func (m *ErrorGroup) GetServiceName() string {
+	if m != nil {
+		return m.ServiceName
+	}
+	return ""
+}
+
+func (m *ErrorGroup) GetMethodName() string {
+	if m != nil {
+		return m.MethodName
+	}
+	return ""
+}
+
+func (m *ErrorGroup) GetErrorCode() uint32 {
+	if m != nil {
+		return m.ErrorCode
+	}
+	return 0
+}
+
+func (m *ErrorGroup) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+func (m *ErrorGroup) GetFirstSeen() uint32 {
+	if m != nil {
+		return m.FirstSeen
+	}
+	return 0
+}
+
+func (m *ErrorGroup) GetLastSeen() uint32 {
+	if m != nil {
+		return m.LastSeen
+	}
+	return 0
+}
+
+func (m *ErrorGroup) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *ErrorGroup) GetUserIDs() []string {
+	if m != nil {
+		return m.UserIDs
+	}
+	return nil
+}
+
+func (m *ErrorGroup) GetSample() *ProtoLog {
+	if m != nil {
+		return m.Sample
+	}
+	return nil
+}
+
+func (m *ErrorGroup) GetState() GroupState {
+	if m != nil {
+		return m.State
+	}
+	return GroupState_OPEN
+}
+
+func (m *ErrorGroup) GetStateChanged() uint32 {
+	if m != nil {
+		return m.StateChanged
+	}
+	return 0
+}
+
+func (m *ErrorGroup) GetMutedUntil() uint32 {
+	if m != nil {
+		return m.MutedUntil
+	}
+	return 0
+}
+
+func (m *ErrorGroup) GetMutedUntilCount() uint64 {
+	if m != nil {
+		return m.MutedUntilCount
+	}
+	return 0
+}
+
+type SetGroupStateRequest struct {
+	Fingerprint     string     `protobuf:"bytes,1,opt,name=Fingerprint" json:"Fingerprint,omitempty"`
+	State           GroupState `protobuf:"varint,2,opt,name=State,enum=errorlogger.GroupState" json:"State,omitempty"`
+	MuteUntil       uint32     `protobuf:"varint,3,opt,name=MuteUntil" json:"MuteUntil,omitempty"`
+	MuteOccurrences uint32     `protobuf:"varint,4,opt,name=MuteOccurrences" json:"MuteOccurrences,omitempty"`
+}
+
+func (m *SetGroupStateRequest) Reset()                    { *m = SetGroupStateRequest{} }
+func (m *SetGroupStateRequest) String() string            { return proto.CompactTextString(m) }
+func (*SetGroupStateRequest) ProtoMessage()               {}
+func (*SetGroupStateRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{7} }
+
+func (m *SetGroupStateRequest) GetFingerprint() string {
+	if m != nil {
+		return m.Fingerprint
+	}
+	return ""
+}
+
+func (m *SetGroupStateRequest) GetState() GroupState {
+	if m != nil {
+		return m.State
+	}
+	return GroupState_OPEN
+}
+
+func (m *SetGroupStateRequest) GetMuteUntil() uint32 {
+	if m != nil {
+		return m.MuteUntil
+	}
+	return 0
+}
+
+func (m *SetGroupStateRequest) GetMuteOccurrences() uint32 {
+	if m != nil {
+		return m.MuteOccurrences
+	}
+	return 0
+}
+
+type ListErrorGroupsRequest struct {
+	Order    GroupOrder   `protobuf:"varint,1,opt,name=Order,enum=errorlogger.GroupOrder" json:"Order,omitempty"`
+	Limit    uint32       `protobuf:"varint,2,opt,name=Limit" json:"Limit,omitempty"`
+	Services []string     `protobuf:"bytes,3,rep,name=Services" json:"Services,omitempty"`
+	States   []GroupState `protobuf:"varint,4,rep,packed,name=States,enum=errorlogger.GroupState" json:"States,omitempty"`
+}
+
+func (m *ListErrorGroupsRequest) Reset()                    { *m = ListErrorGroupsRequest{} }
+func (m *ListErrorGroupsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListErrorGroupsRequest) ProtoMessage()               {}
+func (*ListErrorGroupsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{8} }
+
+func (m *ListErrorGroupsRequest) GetOrder() GroupOrder {
+	if m != nil {
+		return m.Order
+	}
+	return GroupOrder_BY_LAST_SEEN
+}
+
+func (m *ListErrorGroupsRequest) GetLimit() uint32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *ListErrorGroupsRequest) GetServices() []string {
+	if m != nil {
+		return m.Services
+	}
+	return nil
+}
+
+func (m *ListErrorGroupsRequest) GetStates() []GroupState {
+	if m != nil {
+		return m.States
+	}
+	return nil
+}
+
+type ErrorGroupList struct {
+	Groups []*ErrorGroup `protobuf:"bytes,1,rep,name=Groups" json:"Groups,omitempty"`
+}
+
+func (m *ErrorGroupList) Reset()                    { *m = ErrorGroupList{} }
+func (m *ErrorGroupList) String() string            { return proto.CompactTextString(m) }
+func (*ErrorGroupList) ProtoMessage()               {}
+func (*ErrorGroupList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{9} }
+
+func (m *ErrorGroupList) GetGroups() []*ErrorGroup {
+	if m != nil {
+		return m.Groups
+	}
+	return nil
+}
+
+type StatsRequest struct {
+	StartTimestamp uint32       `protobuf:"varint,1,opt,name=StartTimestamp" json:"StartTimestamp,omitempty"`
+	EndTimestamp   uint32       `protobuf:"varint,2,opt,name=EndTimestamp" json:"EndTimestamp,omitempty"`
+	GroupBy        []StatsField `protobuf:"varint,3,rep,packed,name=GroupBy,enum=errorlogger.StatsField" json:"GroupBy,omitempty"`
+	TopN           uint32       `protobuf:"varint,4,opt,name=TopN" json:"TopN,omitempty"`
+}
+
+func (m *StatsRequest) Reset()                    { *m = StatsRequest{} }
+func (m *StatsRequest) String() string            { return proto.CompactTextString(m) }
+func (*StatsRequest) ProtoMessage()               {}
+func (*StatsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{10} }
+
+func (m *StatsRequest) GetStartTimestamp() uint32 {
+	if m != nil {
+		return m.StartTimestamp
+	}
+	return 0
+}
+
+func (m *StatsRequest) GetEndTimestamp() uint32 {
+	if m != nil {
+		return m.EndTimestamp
+	}
+	return 0
+}
+
+func (m *StatsRequest) GetGroupBy() []StatsField {
+	if m != nil {
+		return m.GroupBy
+	}
+	return nil
+}
+
+func (m *StatsRequest) GetTopN() uint32 {
+	if m != nil {
+		return m.TopN
+	}
+	return 0
+}
+
+// the number of errors for one combination of the requested fields. fields which were not requested are not set
+type StatsEntry struct {
+	ServiceName    string `protobuf:"bytes,1,opt,name=ServiceName" json:"ServiceName,omitempty"`
+	MethodName     string `protobuf:"bytes,2,opt,name=MethodName" json:"MethodName,omitempty"`
+	ErrorCode      uint32 `protobuf:"varint,3,opt,name=ErrorCode" json:"ErrorCode,omitempty"`
+	UserID         string `protobuf:"bytes,4,opt,name=UserID" json:"UserID,omitempty"`
+	CallingService string `protobuf:"bytes,5,opt,name=CallingService" json:"CallingService,omitempty"`
+	Count          uint64 `protobuf:"varint,6,opt,name=Count" json:"Count,omitempty"`
+	Team           string `protobuf:"bytes,7,opt,name=Team" json:"Team,omitempty"`
+}
+
+func (m *StatsEntry) Reset()                    { *m = StatsEntry{} }
+func (m *StatsEntry) String() string            { return proto.CompactTextString(m) }
+func (*StatsEntry) ProtoMessage()               {}
+func (*StatsEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{11} }
+
+func (m *StatsEntry) GetServiceName() string {
+	if m != nil {
+		return m.ServiceName
+	}
+	return ""
+}
+
+func (m *StatsEntry) GetMethodName() string {
+	if m != nil {
+		return m.MethodName
+	}
+	return ""
+}
+
+func (m *StatsEntry) GetErrorCode() uint32 {
+	if m != nil {
+		return m.ErrorCode
+	}
+	return 0
+}
+
+func (m *StatsEntry) GetUserID() string {
+	if m != nil {
+		return m.UserID
+	}
+	return ""
+}
+
+func (m *StatsEntry) GetCallingService() string {
+	if m != nil {
+		return m.CallingService
+	}
+	return ""
+}
+
+func (m *StatsEntry) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *StatsEntry) GetTeam() string {
+	if m != nil {
+		return m.Team
+	}
+	return ""
+}
+
+// an alert rule matched
+type Alert struct {
+	Rule        string    `protobuf:"bytes,1,opt,name=Rule" json:"Rule,omitempty"`
+	Description string    `protobuf:"bytes,2,opt,name=Description" json:"Description,omitempty"`
+	Timestamp   uint32    `protobuf:"varint,3,opt,name=Timestamp" json:"Timestamp,omitempty"`
+	Count       uint64    `protobuf:"varint,4,opt,name=Count" json:"Count,omitempty"`
+	Suppressed  uint64    `protobuf:"varint,5,opt,name=Suppressed" json:"Suppressed,omitempty"`
+	Fingerprint string    `protobuf:"bytes,6,opt,name=Fingerprint" json:"Fingerprint,omitempty"`
+	Log         *ProtoLog `protobuf:"bytes,7,opt,name=Log" json:"Log,omitempty"`
+}
+
+func (m *Alert) Reset()                    { *m = Alert{} }
+func (m *Alert) String() string            { return proto.CompactTextString(m) }
+func (*Alert) ProtoMessage()               {}
+func (*Alert) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *Alert) GetRule() string {
+	if m != nil {
+		return m.Rule
+	}
+	return ""
+}
+
+func (m *Alert) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *Alert) GetTimestamp() uint32 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *Alert) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *Alert) GetSuppressed() uint64 {
+	if m != nil {
+		return m.Suppressed
+	}
+	return 0
+}
+
+func (m *Alert) GetFingerprint() string {
+	if m != nil {
+		return m.Fingerprint
+	}
+	return ""
+}
+
+func (m *Alert) GetLog() *ProtoLog {
+	if m != nil {
+		return m.Log
+	}
+	return nil
+}
+
+type AlertRuleState struct {
+	Rule        string `protobuf:"bytes,1,opt,name=Rule" json:"Rule,omitempty"`
+	Description string `protobuf:"bytes,2,opt,name=Description" json:"Description,omitempty"`
+	Firing      bool   `protobuf:"varint,3,opt,name=Firing" json:"Firing,omitempty"`
+	Count       uint64 `protobuf:"varint,4,opt,name=Count" json:"Count,omitempty"`
+	Fired       uint64 `protobuf:"varint,5,opt,name=Fired" json:"Fired,omitempty"`
+	LastAlert   *Alert `protobuf:"bytes,6,opt,name=LastAlert" json:"LastAlert,omitempty"`
+}
+
+func (m *AlertRuleState) Reset()                    { *m = AlertRuleState{} }
+func (m *AlertRuleState) String() string            { return proto.CompactTextString(m) }
+func (*AlertRuleState) ProtoMessage()               {}
+func (*AlertRuleState) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *AlertRuleState) GetRule() string {
+	if m != nil {
+		return m.Rule
+	}
+	return ""
+}
+
+func (m *AlertRuleState) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+func (m *AlertRuleState) GetFiring() bool {
+	if m != nil {
+		return m.Firing
+	}
+	return false
+}
+
+func (m *AlertRuleState) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *AlertRuleState) GetFired() uint64 {
+	if m != nil {
+		return m.Fired
+	}
+	return 0
+}
+
+func (m *AlertRuleState) GetLastAlert() *Alert {
+	if m != nil {
+		return m.LastAlert
+	}
+	return nil
+}
+
+type AlertList struct {
+	Rules []*AlertRuleState `protobuf:"bytes,1,rep,name=Rules" json:"Rules,omitempty"`
+}
+
+func (m *AlertList) Reset()                    { *m = AlertList{} }
+func (m *AlertList) String() string            { return proto.CompactTextString(m) }
+func (*AlertList) ProtoMessage()               {}
+func (*AlertList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *AlertList) GetRules() []*AlertRuleState {
+	if m != nil {
+		return m.Rules
+	}
+	return nil
+}
+
+// the error rate of a service (MethodName empty) or of one of its methods, compared to its baseline
+type Anomaly struct {
+	ServiceName     string  `protobuf:"bytes,1,opt,name=ServiceName" json:"ServiceName,omitempty"`
+	MethodName      string  `protobuf:"bytes,2,opt,name=MethodName" json:"MethodName,omitempty"`
+	Active          bool    `protobuf:"varint,3,opt,name=Active" json:"Active,omitempty"`
+	Baseline        float64 `protobuf:"fixed64,4,opt,name=Baseline" json:"Baseline,omitempty"`
+	Deviation       float64 `protobuf:"fixed64,5,opt,name=Deviation" json:"Deviation,omitempty"`
+	Count           uint64  `protobuf:"varint,6,opt,name=Count" json:"Count,omitempty"`
+	Score           float64 `protobuf:"fixed64,7,opt,name=Score" json:"Score,omitempty"`
+	Started         uint32  `protobuf:"varint,8,opt,name=Started" json:"Started,omitempty"`
+	Samples         uint32  `protobuf:"varint,9,opt,name=Samples" json:"Samples,omitempty"`
+	Interval        uint32  `protobuf:"varint,10,opt,name=Interval" json:"Interval,omitempty"`
+	IntervalSeconds uint32  `protobuf:"varint,11,opt,name=IntervalSeconds" json:"IntervalSeconds,omitempty"`
+}
+
+func (m *Anomaly) Reset()                    { *m = Anomaly{} }
+func (m *Anomaly) String() string            { return proto.CompactTextString(m) }
+func (*Anomaly) ProtoMessage()               {}
+func (*Anomaly) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+func (m *Anomaly) GetServiceName() string {
 	if m != nil {
-		return m.Err
+		return m.ServiceName
 	}
-	return nil
+	return ""
 }
 
-func (m *ProtoLog) GetUser() *auth.User {
+func (m *Anomaly) GetMethodName() string {
 	if m != nil {
-		return m.User
+		return m.MethodName
 	}
-	return nil
+	return ""
 }
 
-func (m *ProtoLog) GetService() *auth.User {
+func (m *Anomaly) GetActive() bool {
 	if m != nil {
-		return m.Service
+		return m.Active
 	}
-	return nil
+	return false
 }
 
-type ErrorLogRequest struct {
-	UserID         string                   `protobuf:"bytes,1,opt,name=UserID" json:"UserID,omitempty"`
-	ServiceName    string                   `protobuf:"bytes,2,opt,name=ServiceName" json:"ServiceName,omitempty"`
-	MethodName     string                   `protobuf:"bytes,3,opt,name=MethodName" json:"MethodName,omitempty"`
-	Timestamp      uint32                   `protobuf:"varint,4,opt,name=Timestamp" json:"Timestamp,omitempty"`
-	ErrorCode      uint32                   `protobuf:"varint,5,opt,name=ErrorCode" json:"ErrorCode,omitempty"`
-	ErrorMessage   string                   `protobuf:"bytes,6,opt,name=ErrorMessage" json:"ErrorMessage,omitempty"`
-	LogMessage     string                   `protobuf:"bytes,7,opt,name=LogMessage" json:"LogMessage,omitempty"`
-	RequestID      string                   `protobuf:"bytes,9,opt,name=RequestID" json:"RequestID,omitempty"`
-	CallingService *auth.User               `protobuf:"bytes,10,opt,name=CallingService" json:"CallingService,omitempty"`
-	Errors         *goeasyops.GRPCErrorList `protobuf:"bytes,11,opt,name=Errors" json:"Errors,omitempty"`
+func (m *Anomaly) GetBaseline() float64 {
+	if m != nil {
+		return m.Baseline
+	}
+	return 0
 }
 
-func (m *ErrorLogRequest) Reset()                    { *m = ErrorLogRequest{} }
-func (m *ErrorLogRequest) String() string            { return proto.CompactTextString(m) }
-func (*ErrorLogRequest) ProtoMessage()               {}
-func (*ErrorLogRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
-
-func (m *ErrorLogRequest) GetUserID() string {
+func (m *Anomaly) GetDeviation() float64 {
 	if m != nil {
-		return m.UserID
+		return m.Deviation
 	}
-	return ""
+	return 0
 }
 
-func (m *ErrorLogRequest) GetServiceName() string {
+func (m *Anomaly) GetCount() uint64 {
 	if m != nil {
-		return m.ServiceName
+		return m.Count
 	}
-	return ""
+	return 0
 }
 
-func (m *ErrorLogRequest) GetMethodName() string {
+func (m *Anomaly) GetScore() float64 {
 	if m != nil {
-		return m.MethodName
+		return m.Score
 	}
-	return ""
+	return 0
 }
 
-func (m *ErrorLogRequest) GetTimestamp() uint32 {
+func (m *Anomaly) GetStarted() uint32 {
 	if m != nil {
-		return m.Timestamp
+		return m.Started
 	}
 	return 0
 }
 
-func (m *ErrorLogRequest) GetErrorCode() uint32 {
+func (m *Anomaly) GetSamples() uint32 {
 	if m != nil {
-		return m.ErrorCode
+		return m.Samples
 	}
 	return 0
 }
 
-func (m *ErrorLogRequest) GetErrorMessage() string {
+func (m *Anomaly) GetInterval() uint32 {
 	if m != nil {
-		return m.ErrorMessage
+		return m.Interval
 	}
-	return ""
+	return 0
 }
 
-func (m *ErrorLogRequest) GetLogMessage() string {
+func (m *Anomaly) GetIntervalSeconds() uint32 {
 	if m != nil {
-		return m.LogMessage
+		return m.IntervalSeconds
 	}
-	return ""
+	return 0
 }
 
-func (m *ErrorLogRequest) GetRequestID() string {
+type GetAnomaliesRequest struct {
+	All      bool     `protobuf:"varint,1,opt,name=All" json:"All,omitempty"`
+	Services []string `protobuf:"bytes,2,rep,name=Services" json:"Services,omitempty"`
+}
+
+func (m *GetAnomaliesRequest) Reset()                    { *m = GetAnomaliesRequest{} }
+func (m *GetAnomaliesRequest) String() string            { return proto.CompactTextString(m) }
+func (*GetAnomaliesRequest) ProtoMessage()               {}
+func (*GetAnomaliesRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *GetAnomaliesRequest) GetAll() bool {
 	if m != nil {
-		return m.RequestID
+		return m.All
 	}
-	return ""
+	return false
 }
 
-func (m *ErrorLogRequest) GetCallingService() *auth.User {
+func (m *GetAnomaliesRequest) GetServices() []string {
 	if m != nil {
-		return m.CallingService
+		return m.Services
 	}
 	return nil
 }
 
-func (m *ErrorLogRequest) GetErrors() *goeasyops.GRPCErrorList {
+type AnomalyList struct {
+	Anomalies []*Anomaly `protobuf:"bytes,1,rep,name=Anomalies" json:"Anomalies,omitempty"`
+}
+
+func (m *AnomalyList) Reset()                    { *m = AnomalyList{} }
+func (m *AnomalyList) String() string            { return proto.CompactTextString(m) }
+func (*AnomalyList) ProtoMessage()               {}
+func (*AnomalyList) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *AnomalyList) GetAnomalies() []*Anomaly {
 	if m != nil {
-		return m.Errors
+		return m.Anomalies
 	}
 	return nil
 }
 
-type ReadLogRequest struct {
-	LogsToSend uint32   `protobuf:"varint,1,opt,name=LogsToSend" json:"LogsToSend,omitempty"`
-	Services   []string `protobuf:"bytes,2,rep,name=Services" json:"Services,omitempty"`
+type StatsResponse struct {
+	StartTimestamp uint32        `protobuf:"varint,1,opt,name=StartTimestamp" json:"StartTimestamp,omitempty"`
+	EndTimestamp   uint32        `protobuf:"varint,2,opt,name=EndTimestamp" json:"EndTimestamp,omitempty"`
+	Total          uint64        `protobuf:"varint,3,opt,name=Total" json:"Total,omitempty"`
+	Entries        []*StatsEntry `protobuf:"bytes,4,rep,name=Entries" json:"Entries,omitempty"`
 }
 
-func (m *ReadLogRequest) Reset()                    { *m = ReadLogRequest{} }
-func (m *ReadLogRequest) String() string            { return proto.CompactTextString(m) }
-func (*ReadLogRequest) ProtoMessage()               {}
-func (*ReadLogRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (m *StatsResponse) Reset()                    { *m = StatsResponse{} }
+func (m *StatsResponse) String() string            { return proto.CompactTextString(m) }
+func (*StatsResponse) ProtoMessage()               {}
+func (*StatsResponse) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
 
-func (m *ReadLogRequest) GetLogsToSend() uint32 {
+func (m *StatsResponse) GetStartTimestamp() uint32 {
 	if m != nil {
-		return m.LogsToSend
+		return m.StartTimestamp
 	}
 	return 0
 }
 
-func (m *ReadLogRequest) GetServices() []string {
+func (m *StatsResponse) GetEndTimestamp() uint32 {
 	if m != nil {
-		return m.Services
+		return m.EndTimestamp
+	}
+	return 0
+}
+
+func (m *StatsResponse) GetTotal() uint64 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+func (m *StatsResponse) GetEntries() []*StatsEntry {
+	if m != nil {
+		return m.Entries
 	}
 	return nil
 }
@@ -186,6 +1091,25 @@ func init() {
 	proto.RegisterType((*ProtoLog)(nil), "errorlogger.ProtoLog")
 	proto.RegisterType((*ErrorLogRequest)(nil), "errorlogger.ErrorLogRequest")
 	proto.RegisterType((*ReadLogRequest)(nil), "errorlogger.ReadLogRequest")
+	proto.RegisterType((*LogBatchRequest)(nil), "errorlogger.LogBatchRequest")
+	proto.RegisterType((*LogAck)(nil), "errorlogger.LogAck")
+	proto.RegisterType((*LogBatchResponse)(nil), "errorlogger.LogBatchResponse")
+	proto.RegisterType((*ErrorGroup)(nil), "errorlogger.ErrorGroup")
+	proto.RegisterType((*SetGroupStateRequest)(nil), "errorlogger.SetGroupStateRequest")
+	proto.RegisterType((*ListErrorGroupsRequest)(nil), "errorlogger.ListErrorGroupsRequest")
+	proto.RegisterType((*ErrorGroupList)(nil), "errorlogger.ErrorGroupList")
+	proto.RegisterType((*StatsRequest)(nil), "errorlogger.StatsRequest")
+	proto.RegisterType((*StatsEntry)(nil), "errorlogger.StatsEntry")
+	proto.RegisterType((*Alert)(nil), "errorlogger.Alert")
+	proto.RegisterType((*AlertRuleState)(nil), "errorlogger.AlertRuleState")
+	proto.RegisterType((*AlertList)(nil), "errorlogger.AlertList")
+	proto.RegisterType((*Anomaly)(nil), "errorlogger.Anomaly")
+	proto.RegisterType((*GetAnomaliesRequest)(nil), "errorlogger.GetAnomaliesRequest")
+	proto.RegisterType((*AnomalyList)(nil), "errorlogger.AnomalyList")
+	proto.RegisterType((*StatsResponse)(nil), "errorlogger.StatsResponse")
+	proto.RegisterEnum("errorlogger.GroupState", GroupState_name, GroupState_value)
+	proto.RegisterEnum("errorlogger.GroupOrder", GroupOrder_name, GroupOrder_value)
+	proto.RegisterEnum("errorlogger.StatsField", StatsField_name, StatsField_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -201,6 +1125,20 @@ const _ = grpc.SupportPackageIsVersion4
 type ErrorLoggerClient interface {
 	// log an error
 	Log(ctx context.Context, in *ErrorLogRequest, opts ...grpc.CallOption) (*common.Void, error)
+	// log several errors at once
+	LogBatch(ctx context.Context, in *LogBatchRequest, opts ...grpc.CallOption) (*LogBatchResponse, error)
+	// log a stream of errors. the response is sent once the client closes the stream
+	LogStream(ctx context.Context, opts ...grpc.CallOption) (ErrorLogger_LogStreamClient, error)
+	// list errors grouped by fingerprint
+	ListErrorGroups(ctx context.Context, in *ListErrorGroupsRequest, opts ...grpc.CallOption) (*ErrorGroupList, error)
+	// acknowledge, resolve, mute or reopen an error group
+	SetGroupState(ctx context.Context, in *SetGroupStateRequest, opts ...grpc.CallOption) (*ErrorGroup, error)
+	// count errors in a time window, grouped by service, method, code, user and/or calling service
+	GetStats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
+	// the state of the alert rules
+	GetAlerts(ctx context.Context, in *common.Void, opts ...grpc.CallOption) (*AlertList, error)
+	// services and methods whose error rate is significantly above their baseline
+	GetAnomalies(ctx context.Context, in *GetAnomaliesRequest, opts ...grpc.CallOption) (*AnomalyList, error)
 	//  rpc SendToServer(stream PingRequest) returns (PingResponse);
 	ReadLog(ctx context.Context, in *ReadLogRequest, opts ...grpc.CallOption) (ErrorLogger_ReadLogClient, error)
 }
@@ -222,8 +1160,96 @@ func (c *errorLoggerClient) Log(ctx context.Context, in *ErrorLogRequest, opts .
 	return out, nil
 }
 
+func (c *errorLoggerClient) LogBatch(ctx context.Context, in *LogBatchRequest, opts ...grpc.CallOption) (*LogBatchResponse, error) {
+	out := new(LogBatchResponse)
+	err := grpc.Invoke(ctx, "/errorlogger.ErrorLogger/LogBatch", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *errorLoggerClient) LogStream(ctx context.Context, opts ...grpc.CallOption) (ErrorLogger_LogStreamClient, error) {
+	stream, err := grpc.NewClientStream(ctx, &_ErrorLogger_serviceDesc.Streams[0], c.cc, "/errorlogger.ErrorLogger/LogStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &errorLoggerLogStreamClient{stream}
+	return x, nil
+}
+
+type ErrorLogger_LogStreamClient interface {
+	Send(*ErrorLogRequest) error
+	CloseAndRecv() (*LogBatchResponse, error)
+	grpc.ClientStream
+}
+
+type errorLoggerLogStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *errorLoggerLogStreamClient) Send(m *ErrorLogRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *errorLoggerLogStreamClient) CloseAndRecv() (*LogBatchResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(LogBatchResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *errorLoggerClient) ListErrorGroups(ctx context.Context, in *ListErrorGroupsRequest, opts ...grpc.CallOption) (*ErrorGroupList, error) {
+	out := new(ErrorGroupList)
+	err := grpc.Invoke(ctx, "/errorlogger.ErrorLogger/ListErrorGroups", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *errorLoggerClient) SetGroupState(ctx context.Context, in *SetGroupStateRequest, opts ...grpc.CallOption) (*ErrorGroup, error) {
+	out := new(ErrorGroup)
+	err := grpc.Invoke(ctx, "/errorlogger.ErrorLogger/SetGroupState", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *errorLoggerClient) GetStats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	out := new(StatsResponse)
+	err := grpc.Invoke(ctx, "/errorlogger.ErrorLogger/GetStats", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *errorLoggerClient) GetAlerts(ctx context.Context, in *common.Void, opts ...grpc.CallOption) (*AlertList, error) {
+	out := new(AlertList)
+	err := grpc.Invoke(ctx, "/errorlogger.ErrorLogger/GetAlerts", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *errorLoggerClient) GetAnomalies(ctx context.Context, in *GetAnomaliesRequest, opts ...grpc.CallOption) (*AnomalyList, error) {
+	out := new(AnomalyList)
+	err := grpc.Invoke(ctx, "/errorlogger.ErrorLogger/GetAnomalies", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *errorLoggerClient) ReadLog(ctx context.Context, in *ReadLogRequest, opts ...grpc.CallOption) (ErrorLogger_ReadLogClient, error) {
-	stream, err := grpc.NewClientStream(ctx, &_ErrorLogger_serviceDesc.Streams[0], c.cc, "/errorlogger.ErrorLogger/ReadLog", opts...)
+	stream, err := grpc.NewClientStream(ctx, &_ErrorLogger_serviceDesc.Streams[1], c.cc, "/errorlogger.ErrorLogger/ReadLog", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -259,6 +1285,20 @@ func (x *errorLoggerReadLogClient) Recv() (*ProtoLog, error) {
 type ErrorLoggerServer interface {
 	// log an error
 	Log(context.Context, *ErrorLogRequest) (*common.Void, error)
+	// log several errors at once
+	LogBatch(context.Context, *LogBatchRequest) (*LogBatchResponse, error)
+	// log a stream of errors. the response is sent once the client closes the stream
+	LogStream(ErrorLogger_LogStreamServer) error
+	// list errors grouped by fingerprint
+	ListErrorGroups(context.Context, *ListErrorGroupsRequest) (*ErrorGroupList, error)
+	// acknowledge, resolve, mute or reopen an error group
+	SetGroupState(context.Context, *SetGroupStateRequest) (*ErrorGroup, error)
+	// count errors in a time window, grouped by service, method, code, user and/or calling service
+	GetStats(context.Context, *StatsRequest) (*StatsResponse, error)
+	// the state of the alert rules
+	GetAlerts(context.Context, *common.Void) (*AlertList, error)
+	// services and methods whose error rate is significantly above their baseline
+	GetAnomalies(context.Context, *GetAnomaliesRequest) (*AnomalyList, error)
 	//  rpc SendToServer(stream PingRequest) returns (PingResponse);
 	ReadLog(*ReadLogRequest, ErrorLogger_ReadLogServer) error
 }
@@ -285,6 +1325,140 @@ func _ErrorLogger_Log_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ErrorLogger_LogBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LogBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ErrorLoggerServer).LogBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/errorlogger.ErrorLogger/LogBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ErrorLoggerServer).LogBatch(ctx, req.(*LogBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ErrorLogger_LogStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ErrorLoggerServer).LogStream(&errorLoggerLogStreamServer{stream})
+}
+
+type ErrorLogger_LogStreamServer interface {
+	SendAndClose(*LogBatchResponse) error
+	Recv() (*ErrorLogRequest, error)
+	grpc.ServerStream
+}
+
+type errorLoggerLogStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *errorLoggerLogStreamServer) SendAndClose(m *LogBatchResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *errorLoggerLogStreamServer) Recv() (*ErrorLogRequest, error) {
+	m := new(ErrorLogRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ErrorLogger_ListErrorGroups_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListErrorGroupsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ErrorLoggerServer).ListErrorGroups(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/errorlogger.ErrorLogger/ListErrorGroups",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ErrorLoggerServer).ListErrorGroups(ctx, req.(*ListErrorGroupsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ErrorLogger_SetGroupState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetGroupStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ErrorLoggerServer).SetGroupState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/errorlogger.ErrorLogger/SetGroupState",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ErrorLoggerServer).SetGroupState(ctx, req.(*SetGroupStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ErrorLogger_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ErrorLoggerServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/errorlogger.ErrorLogger/GetStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ErrorLoggerServer).GetStats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ErrorLogger_GetAlerts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(common.Void)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ErrorLoggerServer).GetAlerts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/errorlogger.ErrorLogger/GetAlerts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ErrorLoggerServer).GetAlerts(ctx, req.(*common.Void))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ErrorLogger_GetAnomalies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAnomaliesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ErrorLoggerServer).GetAnomalies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/errorlogger.ErrorLogger/GetAnomalies",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ErrorLoggerServer).GetAnomalies(ctx, req.(*GetAnomaliesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ErrorLogger_ReadLog_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(ReadLogRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -314,8 +1488,37 @@ var _ErrorLogger_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Log",
 			Handler:    _ErrorLogger_Log_Handler,
 		},
+		{
+			MethodName: "LogBatch",
+			Handler:    _ErrorLogger_LogBatch_Handler,
+		},
+		{
+			MethodName: "ListErrorGroups",
+			Handler:    _ErrorLogger_ListErrorGroups_Handler,
+		},
+		{
+			MethodName: "SetGroupState",
+			Handler:    _ErrorLogger_SetGroupState_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _ErrorLogger_GetStats_Handler,
+		},
+		{
+			MethodName: "GetAlerts",
+			Handler:    _ErrorLogger_GetAlerts_Handler,
+		},
+		{
+			MethodName: "GetAnomalies",
+			Handler:    _ErrorLogger_GetAnomalies_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "LogStream",
+			Handler:       _ErrorLogger_LogStream_Handler,
+			ClientStreams: true,
+		},
 		{
 			StreamName:    "ReadLog",
 			Handler:       _ErrorLogger_ReadLog_Handler,
@@ -330,35 +1533,112 @@ func init() {
 }
 
 var fileDescriptor0 = []byte{
-	// 465 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x8c, 0x53, 0x51, 0x6b, 0xdb, 0x30,
-	0x10, 0xc6, 0x71, 0x97, 0xd4, 0xe7, 0xb6, 0x03, 0xc1, 0x86, 0xf0, 0x4a, 0x09, 0x61, 0x94, 0xb0,
-	0x07, 0xb7, 0xcb, 0xf6, 0xb0, 0xb7, 0xc1, 0xd2, 0x32, 0x0a, 0xe9, 0x08, 0x6a, 0xb7, 0x87, 0xbd,
-	0x69, 0xf1, 0xa1, 0x1a, 0x62, 0x5f, 0x26, 0xb9, 0x1b, 0x7b, 0xdb, 0x7e, 0xf1, 0xfe, 0xc2, 0x90,
-	0x2c, 0x37, 0x4a, 0x03, 0x61, 0x2f, 0xf6, 0xe9, 0xbb, 0xef, 0x74, 0x9f, 0x4e, 0x9f, 0xe0, 0x9d,
-	0xa2, 0xa5, 0xac, 0x55, 0xbe, 0xa0, 0x5a, 0xcb, 0xe2, 0x27, 0x51, 0x91, 0xd7, 0xd8, 0x9c, 0xc9,
-	0x55, 0x69, 0xce, 0x50, 0x6b, 0xd2, 0x4b, 0x52, 0x0a, 0x75, 0x18, 0xe7, 0x2b, 0x4d, 0x0d, 0xb1,
-	0x34, 0x80, 0xb2, 0x7c, 0xc7, 0x36, 0x0b, 0xaa, 0x2a, 0xaa, 0xfd, 0xaf, 0x2d, 0xce, 0x5e, 0xed,
-	0xe0, 0xcb, 0xfb, 0xe6, 0xce, 0x7d, 0x3c, 0xf7, 0xed, 0x0e, 0xae, 0x22, 0x94, 0xe6, 0x17, 0xad,
-	0x82, 0xa8, 0xad, 0x1a, 0xfd, 0x8e, 0x60, 0x7f, 0x6e, 0xa3, 0x19, 0x29, 0x96, 0x43, 0x7c, 0xa9,
-	0x35, 0x8f, 0x86, 0xd1, 0x38, 0x9d, 0x1c, 0xe7, 0xe1, 0x61, 0x2e, 0x6d, 0x3c, 0x23, 0x25, 0xf0,
-	0xfb, 0x3d, 0x9a, 0x46, 0x58, 0x22, 0x3b, 0x81, 0xbd, 0xcf, 0x06, 0x35, 0xef, 0xb9, 0x02, 0xc8,
-	0x9d, 0x1a, 0x8b, 0x08, 0x87, 0xb3, 0x97, 0x30, 0xb8, 0x41, 0xfd, 0xa3, 0x5c, 0x20, 0x8f, 0xb7,
-	0x28, 0x5d, 0x6a, 0xf4, 0xb7, 0x07, 0x4f, 0x1f, 0x6d, 0xcf, 0x9e, 0x43, 0xdf, 0x92, 0xae, 0x2e,
-	0x9c, 0x98, 0x44, 0xf8, 0x15, 0x1b, 0x42, 0xea, 0xcb, 0x3e, 0xc9, 0x0a, 0x5d, 0xe3, 0x44, 0x84,
-	0x10, 0x3b, 0x01, 0xb8, 0xc6, 0xe6, 0x8e, 0x0a, 0x47, 0x88, 0x1d, 0x21, 0x40, 0xd8, 0x31, 0x24,
-	0xb7, 0x65, 0x85, 0xa6, 0x91, 0xd5, 0x8a, 0xef, 0x0d, 0xa3, 0xf1, 0xa1, 0x58, 0x03, 0x36, 0xeb,
-	0xa4, 0x4c, 0xa9, 0x40, 0xfe, 0xa4, 0xcd, 0x3e, 0x00, 0x6c, 0x04, 0x07, 0x6e, 0x71, 0x8d, 0xc6,
-	0x48, 0x85, 0xbc, 0xef, 0x76, 0xdf, 0xc0, 0x6c, 0xff, 0x19, 0xa9, 0x8e, 0x31, 0x68, 0xfb, 0xaf,
-	0x11, 0xdb, 0xc1, 0x1f, 0xf2, 0xea, 0x82, 0x27, 0x2e, 0xbd, 0x06, 0xd8, 0x04, 0x8e, 0xa6, 0x72,
-	0xb9, 0x2c, 0x6b, 0xd5, 0x0d, 0x0e, 0xb6, 0x06, 0xf7, 0x88, 0xc1, 0xce, 0xa1, 0xef, 0x14, 0x18,
-	0x9e, 0x3a, 0x2e, 0xcf, 0xd7, 0x97, 0xfc, 0x51, 0xcc, 0xa7, 0xed, 0x6c, 0x4b, 0xd3, 0x08, 0xcf,
-	0x1b, 0xcd, 0xe0, 0x48, 0xa0, 0x2c, 0x82, 0x79, 0xb7, 0xaa, 0xcd, 0x2d, 0xdd, 0x60, 0x5d, 0xb8,
-	0x99, 0x1f, 0x8a, 0x00, 0x61, 0x19, 0xec, 0xfb, 0x76, 0x86, 0xf7, 0x86, 0xf1, 0x38, 0x11, 0x0f,
-	0xeb, 0xc9, 0x9f, 0x08, 0xd2, 0xee, 0xfe, 0x14, 0x6a, 0xf6, 0x1a, 0x62, 0x6b, 0xa6, 0x9d, 0xfe,
-	0xc9, 0x0e, 0x72, 0x6f, 0xf4, 0x2f, 0x54, 0x16, 0xec, 0x3d, 0x0c, 0xbc, 0x20, 0xf6, 0x62, 0xa3,
-	0x6c, 0x53, 0x66, 0xf6, 0x6c, 0x23, 0xd9, 0xf9, 0xf6, 0x3c, 0xfa, 0x30, 0x87, 0xd3, 0x1a, 0x9b,
-	0xd0, 0xfb, 0xfe, 0x35, 0x58, 0xfb, 0x87, 0x45, 0x5f, 0x4f, 0xff, 0xef, 0x25, 0x7f, 0xeb, 0xbb,
-	0xf7, 0xf1, 0xe6, 0x5f, 0x00, 0x00, 0x00, 0xff, 0xff, 0xf4, 0x8d, 0x3b, 0x18, 0xfa, 0x03, 0x00,
-	0x00,
+	// 1705 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x58, 0x4b, 0x6f, 0xdb, 0xca,
+	0x15, 0xbe, 0x14, 0xa9, 0xd7, 0x91, 0x64, 0x0b, 0x13, 0xd7, 0x97, 0xd5, 0x4d, 0x03, 0x97, 0x2d,
+	0x72, 0x8d, 0xa0, 0x51, 0x12, 0xb7, 0x8b, 0x02, 0x05, 0x5a, 0xc8, 0x12, 0xed, 0xfa, 0x46, 0x7e,
+	0x60, 0x28, 0xa7, 0xbd, 0xdd, 0x04, 0x2c, 0x39, 0x60, 0x88, 0x48, 0x1c, 0x95, 0xa4, 0xdc, 0x78,
+	0xdb, 0x3f, 0xd1, 0x75, 0x81, 0xae, 0xba, 0x69, 0x7f, 0x40, 0x77, 0xfd, 0x0f, 0x5d, 0x15, 0xfd,
+	0x21, 0x5d, 0x15, 0x73, 0x66, 0x28, 0x3e, 0x24, 0x2b, 0xc1, 0x45, 0x36, 0xf6, 0x9c, 0xc7, 0xcc,
+	0x9c, 0xe7, 0x37, 0x47, 0x84, 0x9f, 0x07, 0x7c, 0xee, 0x46, 0xc1, 0xd0, 0xe3, 0x51, 0xec, 0xfa,
+	0x7f, 0xe4, 0xdc, 0x1f, 0x46, 0x2c, 0x7d, 0xe1, 0x2e, 0xc3, 0xe4, 0x05, 0x8b, 0x63, 0x1e, 0xcf,
+	0x79, 0x10, 0xb0, 0xb8, 0xb8, 0x1e, 0x2e, 0x63, 0x9e, 0x72, 0xd2, 0x29, 0xb0, 0x06, 0xc3, 0x1d,
+	0xc7, 0x78, 0x7c, 0xb1, 0xe0, 0x91, 0xfa, 0x27, 0x37, 0x0f, 0x9e, 0xed, 0xd0, 0x77, 0x57, 0xe9,
+	0x3b, 0xfc, 0xa3, 0x74, 0x7f, 0xb6, 0x43, 0x37, 0xe0, 0xcc, 0x4d, 0xee, 0xf9, 0xb2, 0xb0, 0x92,
+	0xbb, 0xac, 0x7f, 0x68, 0xd0, 0xba, 0x11, 0xab, 0x29, 0x0f, 0xc8, 0x10, 0x74, 0x3b, 0x8e, 0x4d,
+	0xed, 0x48, 0x3b, 0xee, 0x9c, 0x3c, 0x1e, 0x16, 0x9d, 0xb1, 0xc5, 0x7a, 0xca, 0x03, 0xca, 0xfe,
+	0xb0, 0x62, 0x49, 0x4a, 0x85, 0x22, 0x79, 0x02, 0xc6, 0x6d, 0xc2, 0x62, 0xb3, 0x86, 0x1b, 0x60,
+	0x88, 0xd6, 0x08, 0x0e, 0x45, 0x3e, 0xf9, 0x31, 0x34, 0x1d, 0x16, 0xdf, 0x85, 0x1e, 0x33, 0xf5,
+	0x0d, 0x95, 0x4c, 0x44, 0x06, 0xd0, 0xa2, 0xcc, 0x63, 0xe1, 0x1d, 0xf3, 0x4d, 0xe3, 0x48, 0x3b,
+	0xee, 0xd1, 0x35, 0x4d, 0x08, 0x18, 0x33, 0xe6, 0x2e, 0xcc, 0xfa, 0x91, 0x76, 0xdc, 0xa6, 0xb8,
+	0xb6, 0xfe, 0xac, 0xc3, 0x7e, 0xc5, 0x1c, 0x72, 0x08, 0x0d, 0x71, 0xe8, 0xc5, 0x04, 0x8d, 0x6f,
+	0x53, 0x45, 0x91, 0x23, 0xe8, 0xa8, 0x6b, 0xae, 0xdc, 0x05, 0x43, 0x43, 0xdb, 0xb4, 0xc8, 0x22,
+	0x4f, 0x00, 0x2e, 0x59, 0xfa, 0x8e, 0xfb, 0xa8, 0xa0, 0xa3, 0x42, 0x81, 0x43, 0x1e, 0x43, 0x7b,
+	0x16, 0x2e, 0x58, 0x92, 0xba, 0x8b, 0xa5, 0x32, 0x2f, 0x67, 0x08, 0x29, 0x9a, 0x32, 0xe6, 0x3e,
+	0x43, 0x23, 0x7b, 0x34, 0x67, 0x10, 0x0b, 0xba, 0x48, 0x5c, 0xb2, 0x24, 0x71, 0x03, 0x66, 0x36,
+	0xf0, 0xf4, 0x12, 0x4f, 0xdc, 0x3f, 0xe5, 0x41, 0xa6, 0xd1, 0x94, 0xf7, 0xe7, 0x1c, 0x71, 0x83,
+	0x72, 0xf2, 0x62, 0x62, 0xb6, 0x51, 0x9c, 0x33, 0xc8, 0x09, 0xec, 0x8d, 0xdd, 0xf9, 0x3c, 0x8c,
+	0x82, 0x2c, 0xd0, 0xb0, 0x11, 0xe8, 0x8a, 0x06, 0x79, 0x09, 0x0d, 0xb4, 0x20, 0x31, 0x3b, 0xa8,
+	0x6b, 0x0e, 0xf3, 0xa2, 0x38, 0xa7, 0x37, 0x63, 0x19, 0xdb, 0x30, 0x49, 0xa9, 0xd2, 0x23, 0x4f,
+	0x61, 0xef, 0xc2, 0x67, 0x8b, 0x25, 0x4f, 0x59, 0xe4, 0xdd, 0xbf, 0x66, 0xf7, 0x66, 0x17, 0x0d,
+	0xa9, 0x70, 0xad, 0x3f, 0xe9, 0xb0, 0x47, 0x99, 0xeb, 0x17, 0x12, 0x23, 0xdd, 0x4b, 0x66, 0xdc,
+	0x61, 0x91, 0x8f, 0xc9, 0xe9, 0xd1, 0x02, 0x47, 0x24, 0x5f, 0xd9, 0x95, 0x98, 0xb5, 0x23, 0xfd,
+	0xb8, 0x4d, 0xd7, 0x34, 0x31, 0xa1, 0x29, 0xd3, 0x98, 0x98, 0x3a, 0x8a, 0x32, 0x52, 0x48, 0x64,
+	0x8a, 0x12, 0xd3, 0x90, 0x12, 0x45, 0x8a, 0xfb, 0xd6, 0xf1, 0x4f, 0xcc, 0xfa, 0x91, 0x2e, 0xee,
+	0xcb, 0x39, 0xe4, 0x18, 0xf6, 0xcb, 0xe1, 0x48, 0xcc, 0x06, 0x9e, 0x50, 0x65, 0x97, 0x03, 0xdf,
+	0xac, 0x06, 0x1e, 0x0b, 0xf3, 0x43, 0x6a, 0xb6, 0xb2, 0xc2, 0xfc, 0x90, 0x8a, 0x30, 0x39, 0xa9,
+	0x1b, 0xa7, 0x79, 0xbd, 0xb4, 0xd1, 0xdf, 0x0a, 0x17, 0xcb, 0x22, 0xf2, 0x73, 0x2d, 0x40, 0xad,
+	0x12, 0x4f, 0xe8, 0x5c, 0x44, 0xde, 0x7c, 0xe5, 0xb3, 0xcb, 0x55, 0xca, 0x7c, 0x4c, 0x55, 0x8b,
+	0x96, 0x78, 0xe4, 0x00, 0xea, 0xa2, 0x21, 0x12, 0xb3, 0x8b, 0x1e, 0x48, 0xc2, 0x1a, 0xc3, 0xfe,
+	0x94, 0x07, 0xa7, 0x6e, 0xea, 0xbd, 0xcb, 0x92, 0xf0, 0x12, 0x0c, 0x11, 0x72, 0x53, 0x3b, 0xd2,
+	0x3f, 0xda, 0xd8, 0xa8, 0x69, 0xdd, 0x40, 0x63, 0xca, 0x83, 0x91, 0xf7, 0x5e, 0x5c, 0x72, 0x11,
+	0xf9, 0xec, 0x83, 0xca, 0x9d, 0x24, 0x44, 0xda, 0x46, 0x9e, 0xc7, 0x96, 0xc2, 0xb4, 0x1a, 0x9a,
+	0xb6, 0xa6, 0xc5, 0x0e, 0x3c, 0x54, 0x35, 0x93, 0x24, 0xac, 0x5f, 0x40, 0x3f, 0x37, 0x2b, 0x59,
+	0xf2, 0x28, 0x61, 0xe4, 0x6b, 0x30, 0x46, 0xde, 0xfb, 0xcc, 0xae, 0x47, 0x25, 0xbb, 0xe4, 0xf5,
+	0x14, 0x15, 0xac, 0xff, 0xea, 0x2a, 0xad, 0xe7, 0x31, 0x5f, 0x2d, 0x45, 0x57, 0x9f, 0x85, 0x51,
+	0xc0, 0xe2, 0x65, 0x1c, 0x46, 0xa9, 0x6a, 0xf9, 0x22, 0xeb, 0xf3, 0xf4, 0x7d, 0xde, 0xd9, 0x46,
+	0xb5, 0xb3, 0xb1, 0x00, 0x65, 0xcb, 0x4a, 0x68, 0x6a, 0x16, 0xfa, 0xf5, 0x2c, 0x8c, 0x93, 0xd4,
+	0x61, 0x2c, 0xc2, 0x86, 0xef, 0xd1, 0x9c, 0x21, 0xe2, 0x36, 0x75, 0x95, 0xb0, 0x29, 0xb1, 0x2e,
+	0xa3, 0x45, 0xdc, 0xc6, 0x7c, 0x15, 0xc9, 0x9a, 0x32, 0xa8, 0x24, 0x8a, 0x4d, 0xd0, 0x2e, 0x37,
+	0xc1, 0x73, 0x68, 0x38, 0xee, 0x62, 0x39, 0xcf, 0x7a, 0xfe, 0x7b, 0xa5, 0xf8, 0x65, 0xa0, 0x4e,
+	0x95, 0x12, 0x79, 0x0e, 0x75, 0x27, 0x75, 0x53, 0x86, 0xa5, 0xb4, 0x77, 0xf2, 0x65, 0x49, 0x1b,
+	0xe3, 0x8a, 0x62, 0x2a, 0xb5, 0x44, 0x01, 0xe2, 0x62, 0xfc, 0xce, 0x8d, 0x02, 0xe6, 0x63, 0xc7,
+	0xf7, 0x68, 0x89, 0x87, 0x31, 0x14, 0x95, 0x78, 0x1b, 0xa5, 0xe1, 0xdc, 0xec, 0xc9, 0xe6, 0xce,
+	0x39, 0xa2, 0xd9, 0x72, 0x4a, 0xfa, 0xb6, 0x87, 0xbe, 0x55, 0xd9, 0xd6, 0xdf, 0x35, 0x38, 0x70,
+	0x58, 0x5a, 0x30, 0x43, 0x95, 0xee, 0xc7, 0x53, 0xbd, 0xf6, 0xab, 0xf6, 0x49, 0x7e, 0x3d, 0x86,
+	0xb6, 0xb8, 0x5c, 0x9a, 0xac, 0xcb, 0xfc, 0xac, 0x19, 0x99, 0xc5, 0xd7, 0x9e, 0xb7, 0x8a, 0x63,
+	0x16, 0x09, 0x78, 0x90, 0xb9, 0xaf, 0xb2, 0xad, 0xbf, 0x69, 0x70, 0x28, 0x40, 0x32, 0x2f, 0xcb,
+	0x24, 0xb3, 0xf9, 0x39, 0xd4, 0xaf, 0x63, 0x9f, 0xc9, 0x87, 0x74, 0xab, 0x45, 0x28, 0xa6, 0x52,
+	0x4b, 0xe4, 0x7d, 0x1a, 0x2e, 0xc2, 0x14, 0x1d, 0xe8, 0x51, 0x49, 0x94, 0x80, 0x51, 0xaf, 0x00,
+	0xe3, 0x0b, 0x68, 0xa0, 0x33, 0x12, 0xfd, 0x76, 0xf8, 0xac, 0xd4, 0xac, 0x11, 0xec, 0xe5, 0x76,
+	0x0a, 0xab, 0xc5, 0x11, 0xd2, 0x68, 0xd5, 0x7c, 0x5f, 0x6e, 0x82, 0x02, 0xca, 0xa9, 0x52, 0xb3,
+	0xfe, 0xa2, 0xc9, 0x82, 0x58, 0x7b, 0xb9, 0x89, 0x76, 0xda, 0x27, 0xa1, 0x5d, 0x6d, 0x0b, 0xda,
+	0xbd, 0x82, 0x26, 0x5e, 0x73, 0x7a, 0x6f, 0xea, 0x5b, 0x3c, 0xc2, 0x7b, 0xcf, 0x42, 0x36, 0xf7,
+	0x69, 0xa6, 0x87, 0x00, 0xcc, 0x97, 0x57, 0x2a, 0x3d, 0xb8, 0xb6, 0xfe, 0xad, 0x01, 0xa0, 0xae,
+	0x1d, 0xa5, 0xf1, 0x7d, 0x15, 0x04, 0xb4, 0x8f, 0x81, 0x40, 0x6d, 0x37, 0x08, 0xe8, 0x55, 0x10,
+	0xc8, 0x87, 0x0e, 0xa3, 0x34, 0x74, 0x3c, 0xdd, 0x78, 0x94, 0x25, 0x46, 0x54, 0xb8, 0x79, 0xc3,
+	0x37, 0x8a, 0x0d, 0x9f, 0x8d, 0x3c, 0xcd, 0xc2, 0xc8, 0xf3, 0x1f, 0x0d, 0xea, 0xa3, 0x39, 0x8b,
+	0x51, 0x4a, 0x57, 0xf3, 0xcc, 0x19, 0x5c, 0x0b, 0x3f, 0x27, 0x2c, 0xf1, 0xe2, 0x70, 0x99, 0x86,
+	0x3c, 0xca, 0xc0, 0xae, 0xc0, 0x2a, 0x0f, 0x31, 0x7a, 0x75, 0x88, 0x59, 0xdb, 0x61, 0x14, 0xed,
+	0x78, 0x02, 0xe0, 0xac, 0x96, 0xcb, 0x98, 0x25, 0x09, 0xf3, 0xd1, 0x03, 0x83, 0x16, 0x38, 0xd5,
+	0xce, 0x6c, 0x6c, 0x76, 0xe6, 0xd7, 0xa0, 0x4f, 0x79, 0x60, 0x36, 0x77, 0xa1, 0x93, 0xd0, 0xb0,
+	0xfe, 0xa9, 0xc1, 0x1e, 0xba, 0x27, 0xdc, 0x91, 0x6d, 0xfa, 0xdd, 0xfc, 0x3c, 0x84, 0xc6, 0x59,
+	0x18, 0x87, 0x51, 0x80, 0x4e, 0xb6, 0xa8, 0xa2, 0x1e, 0xf0, 0xf0, 0x00, 0xea, 0x67, 0x61, 0xbc,
+	0x76, 0x4e, 0x12, 0xe4, 0x25, 0xb4, 0x05, 0x24, 0xa3, 0x3d, 0xe8, 0x55, 0xe7, 0x84, 0x94, 0x6c,
+	0x97, 0x96, 0xe6, 0x4a, 0xd6, 0x2f, 0xa1, 0x8d, 0x0b, 0x6c, 0xac, 0x57, 0x50, 0x17, 0xc6, 0x66,
+	0x7d, 0xf5, 0xd5, 0x96, 0xad, 0x99, 0x93, 0x54, 0x6a, 0x5a, 0xff, 0xaa, 0x41, 0x73, 0x14, 0xf1,
+	0x85, 0x3b, 0xff, 0x1c, 0x35, 0x7b, 0x08, 0x8d, 0x91, 0x97, 0x86, 0x77, 0x2c, 0x8b, 0x81, 0xa4,
+	0x04, 0xa0, 0x9c, 0xba, 0x09, 0x9b, 0x87, 0x91, 0x7c, 0xcf, 0x34, 0xba, 0xa6, 0x45, 0x7d, 0x4c,
+	0xd8, 0x5d, 0xe8, 0x62, 0x5c, 0xeb, 0x28, 0xcc, 0x19, 0x0f, 0xd4, 0xe9, 0x01, 0xd4, 0x1d, 0x8f,
+	0xc7, 0x72, 0x66, 0xd5, 0xa8, 0x24, 0xc4, 0x73, 0x85, 0xfd, 0xcf, 0x7c, 0x7c, 0xc6, 0x7a, 0x34,
+	0x23, 0x51, 0x82, 0x2f, 0x51, 0xa2, 0xc6, 0xa2, 0x8c, 0x14, 0x96, 0x5d, 0x44, 0x29, 0x8b, 0xef,
+	0xdc, 0xb9, 0x9a, 0x85, 0xd6, 0xb4, 0x00, 0xe4, 0x6c, 0xed, 0x30, 0x8f, 0x47, 0xbe, 0x9c, 0x5a,
+	0x7b, 0xb4, 0xca, 0xb6, 0xc6, 0xf0, 0xe8, 0x9c, 0xa5, 0x32, 0x8e, 0x21, 0x5b, 0xc3, 0x54, 0x1f,
+	0xf4, 0xd1, 0x7c, 0x8e, 0x81, 0x6c, 0x51, 0xb1, 0xdc, 0x35, 0x72, 0x5a, 0x23, 0xe8, 0xa8, 0x4c,
+	0x60, 0x32, 0x4f, 0xa0, 0xbd, 0x3e, 0x50, 0x25, 0xf4, 0xa0, 0x9c, 0x50, 0xa9, 0x4c, 0x73, 0x35,
+	0xeb, 0xaf, 0x1a, 0xf4, 0x14, 0x50, 0xaa, 0x31, 0xe7, 0x73, 0x22, 0xa5, 0x98, 0xf9, 0x78, 0xea,
+	0xca, 0xa7, 0xcb, 0xa0, 0x92, 0x10, 0xf8, 0x29, 0x20, 0x2f, 0x54, 0x2f, 0x42, 0x67, 0x1b, 0x7e,
+	0x22, 0x26, 0xd2, 0x4c, 0xef, 0xd9, 0x0d, 0x40, 0xfe, 0x50, 0x90, 0x16, 0x18, 0xd7, 0x37, 0xf6,
+	0x55, 0xff, 0x0b, 0xd2, 0x87, 0xee, 0x68, 0xfc, 0xfa, 0xea, 0xfa, 0x37, 0x53, 0x7b, 0x72, 0x6e,
+	0x4f, 0xfa, 0x1a, 0xe9, 0x42, 0x8b, 0xda, 0xce, 0xf5, 0xf4, 0x8d, 0x3d, 0xe9, 0xd7, 0x48, 0x1b,
+	0xea, 0x97, 0xb7, 0x33, 0x7b, 0xd2, 0xd7, 0x49, 0x0f, 0xda, 0xd4, 0x3e, 0xa7, 0xb6, 0xe3, 0xd8,
+	0x93, 0xbe, 0xf1, 0xec, 0x27, 0xea, 0x44, 0xf9, 0xaa, 0xf5, 0xa1, 0x7b, 0xfa, 0xed, 0xdb, 0xe9,
+	0xc8, 0x99, 0xbd, 0x75, 0x6c, 0x3c, 0xb9, 0x0b, 0xad, 0xd3, 0x6f, 0xdf, 0x8e, 0xaf, 0x6f, 0xaf,
+	0x66, 0x7d, 0xed, 0xd9, 0x6f, 0x01, 0x72, 0x58, 0x27, 0x1d, 0x68, 0x3a, 0x36, 0x7d, 0x73, 0x31,
+	0xb6, 0xfb, 0x5f, 0x10, 0x80, 0xc6, 0xa5, 0x3d, 0xfb, 0xf5, 0xb5, 0xb8, 0xbc, 0x05, 0xc6, 0xf8,
+	0x7a, 0x62, 0xf7, 0x6b, 0x62, 0x75, 0xeb, 0xd8, 0xb4, 0xaf, 0x93, 0x47, 0xb0, 0x3f, 0x1e, 0x4d,
+	0xa7, 0x17, 0x57, 0xe7, 0x6f, 0xb3, 0x4d, 0x86, 0x10, 0xcf, 0xec, 0xd1, 0x65, 0xbf, 0x7e, 0xf2,
+	0x3f, 0x03, 0x3a, 0xd9, 0x54, 0x1b, 0xb0, 0x98, 0xbc, 0x42, 0x18, 0x22, 0x3b, 0xc7, 0xde, 0x41,
+	0x77, 0xa8, 0x7e, 0x78, 0xbf, 0xe1, 0xa1, 0x4f, 0xce, 0xa1, 0x95, 0x0d, 0xab, 0x95, 0x7d, 0x95,
+	0xd1, 0x7a, 0xf0, 0x83, 0x07, 0xa4, 0x2a, 0xf5, 0xdf, 0x40, 0x7b, 0xca, 0x03, 0x27, 0x8d, 0x99,
+	0xbb, 0xf8, 0x88, 0x05, 0xbb, 0x4f, 0x3a, 0xd6, 0x88, 0x03, 0xfb, 0x95, 0x81, 0x83, 0xfc, 0xa8,
+	0xbc, 0x67, 0xeb, 0x38, 0x32, 0xf8, 0xea, 0x81, 0xa7, 0x1d, 0x2b, 0xfc, 0x35, 0xf4, 0x4a, 0x73,
+	0x17, 0xf9, 0x61, 0xb9, 0x72, 0xb6, 0xcc, 0x64, 0x83, 0x87, 0x66, 0x05, 0x32, 0x82, 0xd6, 0x39,
+	0x4b, 0x31, 0xad, 0xe4, 0xfb, 0x9b, 0x15, 0x98, 0xed, 0x1f, 0x6c, 0x13, 0xa9, 0x80, 0xbd, 0x82,
+	0xb6, 0xe8, 0x62, 0x81, 0x93, 0x09, 0x29, 0x25, 0x65, 0x70, 0xb8, 0x09, 0xa5, 0xe8, 0xc2, 0x37,
+	0xd0, 0x2d, 0x36, 0x3e, 0x39, 0x2a, 0x4f, 0x43, 0x9b, 0x98, 0x30, 0x30, 0xb7, 0xf5, 0x30, 0x9e,
+	0xf5, 0x2b, 0x68, 0xaa, 0x1f, 0xb0, 0xa4, 0x1c, 0xb6, 0xf2, 0xcf, 0xda, 0xc1, 0xf6, 0xd7, 0xec,
+	0xa5, 0x76, 0x7a, 0x03, 0x4f, 0x23, 0x96, 0x16, 0x3f, 0xc2, 0xa8, 0xcf, 0x32, 0xe2, 0x3b, 0x4c,
+	0x71, 0xd3, 0xef, 0x9e, 0x7e, 0xda, 0x27, 0xa5, 0xdf, 0x37, 0xf0, 0x43, 0xcd, 0x4f, 0xff, 0x3f,
+	0x00, 0xce, 0x70, 0x08, 0x68, 0x83, 0x12, 0x00, 0x00,
 }
@@ -0,0 +1,121 @@
+package alerting
+
+import (
+	"testing"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+)
+
+const test_rules = `
+rules:
+  - name: foo-internal
+    description: more than 2 Internal errors from foo in 10 seconds
+    services: [FOO]
+    codes: [13]
+    threshold: 2
+    window: 10s
+    debounce: 20s
+  - name: user-1
+    users: ["1"]
+  - name: new
+    new_fingerprint: true
+    debounce: 1s
+`
+
+func newLog(service string, code uint32, user string, ts uint32) *pb.ProtoLog {
+	return &pb.ProtoLog{
+		Err:      &pb.ErrorLogRequest{ServiceName: service, ErrorCode: code, UserID: user},
+		Received: ts,
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules([]byte(test_rules))
+	if err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+	if len(rules) != 3 || rules[0].Services[0] != "foo" || rules[1].Window.Minutes() != 5 || rules[1].Debounce != rules[1].Window {
+		t.Errorf("wrong rules or defaults: %v %v", rules[0], rules[1])
+	}
+	for _, bad := range []string{
+		"rules:\n  - description: no name\n",
+		"rules:\n  - name: a\n  - name: a\n",
+		"rules:\n  - name: a\n    window: 10ms\n",
+		"rules:\n  - name: a\n    unknown_field: 1\n",
+	} {
+		_, err := ParseRules([]byte(bad))
+		if err == nil {
+			t.Errorf("invalid rules accepted: %s", bad)
+		}
+	}
+}
+
+func TestEngine(t *testing.T) {
+	rules, err := ParseRules([]byte(test_rules))
+	if err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+	var alerts []*pb.Alert
+	e := &Engine{OnAlert: func(a *pb.Alert) { alerts = append(alerts, a) }}
+	e.SetRules(rules)
+
+	// threshold within the window
+	e.Process(newLog("foo.Foo", 13, "", 100), "a", false)
+	e.Process(newLog("foo.Foo", 13, "", 105), "a", false)
+	e.Process(newLog("foo.Foo", 5, "", 106), "a", false)  // wrong code
+	e.Process(newLog("foo.Foo", 13, "", 111), "a", false) // 100 is outside the window
+	if len(alerts) != 0 {
+		t.Fatalf("unexpected alerts: %v", alerts)
+	}
+	e.Process(newLog("foo.Foo", 13, "", 112), "a", false)
+	if len(alerts) != 1 || alerts[0].Rule != "foo-internal" || alerts[0].Count != 3 {
+		t.Fatalf("expected one alert with count 3, got %v", alerts)
+	}
+	// debounced
+	e.Process(newLog("foo.Foo", 13, "", 113), "a", false)
+	e.Process(newLog("foo.Foo", 13, "", 120), "a", false)
+	if len(alerts) != 1 {
+		t.Fatalf("expected repeats to be debounced, got %v", alerts)
+	}
+	e.Process(newLog("foo.Foo", 13, "", 121), "a", false)
+	e.Process(newLog("foo.Foo", 13, "", 132), "a", false) // 113-121 are outside the window
+	e.Process(newLog("foo.Foo", 13, "", 133), "a", false)
+	e.Process(newLog("foo.Foo", 13, "", 134), "a", false)
+	if len(alerts) != 2 || alerts[1].Suppressed != 3 || alerts[1].Count != 3 {
+		t.Fatalf("expected alert after debounce with 3 suppressed, got %v", alerts)
+	}
+
+	// any error for a user, new fingerprints
+	alerts = nil
+	pl := newLog("bar.Bar", 5, "1", 200)
+	e.Process(pl, "b", true)
+	if len(alerts) != 2 || alerts[0].Rule != "user-1" || alerts[1].Rule != "new" || alerts[1].Fingerprint != "b" {
+		t.Fatalf("expected user-1 and new alerts, got %v", alerts)
+	}
+	// alerts do not share the log with the caller
+	pl.Received = 300
+	if alerts[0].Log == pl || alerts[0].Log.Received != 200 {
+		t.Errorf("alert log changed with the caller's log: %v", alerts[0].Log)
+	}
+	e.Process(newLog("bar.Bar", 5, "2", 201), "b", false)
+	if len(alerts) != 2 {
+		t.Fatalf("unexpected alerts: %v", alerts[2:])
+	}
+
+	// state
+	state := e.State(205)
+	if len(state) != 3 || state[0].Firing || !state[1].Firing || state[1].Count != 1 || state[0].Fired != 2 {
+		t.Errorf("wrong state: %v", state)
+	}
+	if e.State(1000)[1].Firing {
+		t.Errorf("rule still firing after the window")
+	}
+
+	// state is kept across reloads
+	rules, _ = ParseRules([]byte(test_rules))
+	e.SetRules(rules[:2])
+	state = e.State(1000)
+	if len(state) != 2 || state[0].Fired != 2 || state[0].LastAlert == nil {
+		t.Errorf("state lost on reload: %v", state)
+	}
+}
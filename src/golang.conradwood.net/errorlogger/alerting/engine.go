@@ -0,0 +1,145 @@
+/*
+evaluates alert rules over sliding windows as errors arrive.
+Rules are loaded from a yaml file (see Rule) and can be replaced at any time, the state of rules which are kept is preserved
+*/
+package alerting
+
+import (
+	"sync"
+	"time"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+)
+
+type Engine struct {
+	OnAlert func(a *pb.Alert) // called for every alert raised. must not block for long, it is called on the ingest path
+	lock    sync.Mutex
+	rules   []*ruleState
+}
+
+type ruleState struct {
+	rule       *Rule
+	buckets    []bucket // oldest first
+	count      uint64   // sum of buckets
+	fired      uint64
+	suppressed uint64 // matches above the threshold since the last alert
+	last_fired uint32
+	last_alert *pb.Alert
+}
+
+// matching errors received within one second
+type bucket struct {
+	ts uint32
+	n  uint64
+}
+
+// replace the rules. rules with the same name as a previous one keep their window and debounce state
+func (e *Engine) SetRules(rules []*Rule) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	old := make(map[string]*ruleState)
+	for _, rs := range e.rules {
+		old[rs.rule.Name] = rs
+	}
+	var res []*ruleState
+	for _, r := range rules {
+		rs := old[r.Name]
+		if rs == nil {
+			rs = &ruleState{}
+		}
+		rs.rule = r
+		res = append(res, rs)
+	}
+	e.rules = res
+}
+
+// evaluate the rules for an error. newFingerprint is true if it is the first error of its group.
+// Alerts keep a copy of pl, the caller may go on using it
+func (e *Engine) Process(pl *pb.ProtoLog, fingerprint string, newFingerprint bool) {
+	ts := pl.Received
+	if ts == 0 && pl.Err != nil {
+		ts = pl.Err.Timestamp
+	}
+	var alerts []*pb.Alert
+	e.lock.Lock()
+	for _, rs := range e.rules {
+		if !rs.rule.Match(pl, newFingerprint) {
+			continue
+		}
+		rs.add(ts)
+		if rs.count <= rs.rule.Threshold {
+			continue
+		}
+		if rs.fired > 0 && ts < rs.last_fired+uint32(rs.rule.Debounce/time.Second) {
+			rs.suppressed++
+			continue
+		}
+		log := *pl
+		a := &pb.Alert{
+			Rule:        rs.rule.Name,
+			Description: rs.rule.Description,
+			Timestamp:   ts,
+			Count:       rs.count,
+			Suppressed:  rs.suppressed,
+			Fingerprint: fingerprint,
+			Log:         &log,
+		}
+		rs.fired++
+		rs.suppressed = 0
+		rs.last_fired = ts
+		rs.last_alert = a
+		alerts = append(alerts, a)
+	}
+	e.lock.Unlock()
+	if e.OnAlert == nil {
+		return
+	}
+	for _, a := range alerts {
+		e.OnAlert(a)
+	}
+}
+
+// the state of all rules at the given time
+func (e *Engine) State(now uint32) []*pb.AlertRuleState {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	var res []*pb.AlertRuleState
+	for _, rs := range e.rules {
+		rs.expire(now)
+		res = append(res, &pb.AlertRuleState{
+			Rule:        rs.rule.Name,
+			Description: rs.rule.Description,
+			Firing:      rs.count > rs.rule.Threshold,
+			Count:       rs.count,
+			Fired:       rs.fired,
+			LastAlert:   rs.last_alert,
+		})
+	}
+	return res
+}
+
+// count a matching error received at ts
+func (rs *ruleState) add(ts uint32) {
+	l := len(rs.buckets)
+	if l > 0 && ts <= rs.buckets[l-1].ts {
+		// same second, or slightly out of order
+		rs.buckets[l-1].n++
+	} else {
+		rs.buckets = append(rs.buckets, bucket{ts: ts, n: 1})
+	}
+	rs.count++
+	rs.expire(ts)
+}
+
+// forget errors which are no longer within the window at now
+func (rs *ruleState) expire(now uint32) {
+	window := uint32(rs.rule.Window / time.Second)
+	i := 0
+	for i < len(rs.buckets) && rs.buckets[i].ts+window <= now {
+		rs.count -= rs.buckets[i].n
+		i++
+	}
+	if i > 0 {
+		rs.buckets = append(rs.buckets[:0], rs.buckets[i:]...)
+	}
+}
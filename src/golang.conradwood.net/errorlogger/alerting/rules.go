@@ -0,0 +1,146 @@
+package alerting
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/go-easyops/utils"
+	"gopkg.in/yaml.v2"
+)
+
+/*
+an alert rule. An alert is raised if more than Threshold matching errors are received within Window.
+All criteria that are set must match. If a criterion has multiple values, any one of them may match.
+for example:
+
+	rules:
+	  - name: foo-internal
+	    description: more than 50 Internal errors from foo in 5 minutes
+	    services: [foo.Foo]
+	    codes: [13]
+	    threshold: 50
+	    window: 5m
+	  - name: new-errors
+	    new_fingerprint: true
+	    debounce: 1h
+*/
+type Rule struct {
+	Name           string        `yaml:"name"` // unique
+	Description    string        `yaml:"description"`
+	Services       []string      `yaml:"services"` // case-insensitive substrings of the service name
+	Methods        []string      `yaml:"methods"`  // case-insensitive substrings of the method name
+	Codes          []uint32      `yaml:"codes"`    // grpc error codes
+	Users          []string      `yaml:"users"`    // userids
+	NewFingerprint bool          `yaml:"new_fingerprint"`
+	Threshold      uint64        `yaml:"threshold"` // 0 alerts on any matching error
+	Window         time.Duration `yaml:"window"`    // defaults to 5 minutes
+	Debounce       time.Duration `yaml:"debounce"`  // minimum time between two alerts of this rule. defaults to Window
+}
+
+type ruleFile struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// read rules from a yaml file
+func LoadRules(filename string) ([]*Rule, error) {
+	bs, err := utils.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := ParseRules(bs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return rules, nil
+}
+
+// parse and check rules in yaml
+func ParseRules(bs []byte) ([]*Rule, error) {
+	rf := &ruleFile{}
+	err := yaml.UnmarshalStrict(bs, rf)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for i, r := range rf.Rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("rule #%d has no name", i+1)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("duplicate rule \"%s\"", r.Name)
+		}
+		names[r.Name] = true
+		if r.Window < 0 || r.Debounce < 0 {
+			return nil, fmt.Errorf("rule \"%s\": negative duration", r.Name)
+		}
+		if r.Window == 0 {
+			r.Window = 5 * time.Minute
+		}
+		if r.Window < time.Second {
+			return nil, fmt.Errorf("rule \"%s\": window must be at least one second", r.Name)
+		}
+		if r.Debounce == 0 {
+			r.Debounce = r.Window
+		}
+		for j, s := range r.Services {
+			r.Services[j] = strings.ToLower(s)
+		}
+		for j, m := range r.Methods {
+			r.Methods[j] = strings.ToLower(m)
+		}
+	}
+	return rf.Rules, nil
+}
+
+// true if the rule applies to the error. newFingerprint is true if it is the first error of its group
+func (r *Rule) Match(pl *pb.ProtoLog, newFingerprint bool) bool {
+	e := pl.Err
+	if e == nil {
+		return false
+	}
+	if r.NewFingerprint && !newFingerprint {
+		return false
+	}
+	if len(r.Services) != 0 && !containsAny(strings.ToLower(e.ServiceName), r.Services) {
+		return false
+	}
+	if len(r.Methods) != 0 && !containsAny(strings.ToLower(e.MethodName), r.Methods) {
+		return false
+	}
+	if len(r.Codes) != 0 && !containsCode(r.Codes, e.ErrorCode) {
+		return false
+	}
+	if len(r.Users) != 0 && !containsString(r.Users, e.UserID) {
+		return false
+	}
+	return true
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsCode(codes []uint32, code uint32) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(l []string, s string) bool {
+	for _, x := range l {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,323 @@
+/*
+detects spikes in the error rate of services and methods.
+Errors are counted per interval (e.g. per minute). The counts of past intervals form a baseline, an exponentially
+weighted moving average with its deviation. An anomaly is detected when the count of the current interval is more
+than Threshold deviations above the baseline, as soon as the error which crosses the threshold is added.
+An anomaly ends when an interval closes with a normal count. While the count of the current interval is still
+incomplete, it is not known whether the spike is over, so an anomaly stays active until the end of the first normal
+interval, i.e. up to one interval after the error rate returned to normal
+*/
+package anomaly
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/go-easyops/utils"
+)
+
+const (
+	max_idle_intervals = 10000 // past this, a baseline is treated as zero rather than decayed interval by interval
+)
+
+type Detector struct {
+	Interval   time.Duration       // defaults to 1 minute
+	Alpha      float64             // weight of the most recent interval in the baseline. defaults to 0.02
+	Threshold  float64             // deviations above baseline which count as anomaly. defaults to 4
+	MinCount   uint64              // errors in an interval needed for an anomaly. defaults to 10
+	MinSamples uint32              // intervals needed for a baseline before anomalies are detected. defaults to 30
+	MaxKeys    int                 // services and methods tracked. the least recently seen are forgotten. defaults to 10000
+	OnAnomaly  func(a *pb.Anomaly) // called when an anomaly is detected. must not block for long, it is called on the ingest path
+	lock       sync.Mutex
+	start      sync.Once
+	baselines  map[key]*baseline
+	lru        *list.List // keys, most recently seen first
+	seconds    uint32     // Interval in seconds
+}
+
+type key struct {
+	service string
+	method  string // empty for the service as a whole
+}
+
+type baseline struct {
+	mean      float64
+	variance  float64
+	samples   uint32
+	interval  uint32 // start of the current interval
+	count     uint64 // errors in the current interval
+	active    bool
+	started   uint32
+	last_seen uint32
+	el        *list.Element // in Detector.lru
+}
+
+func (d *Detector) init() {
+	d.start.Do(func() {
+		if d.Interval < time.Second {
+			d.Interval = time.Minute
+		}
+		if d.Alpha <= 0 || d.Alpha > 1 {
+			d.Alpha = 0.02
+		}
+		if d.Threshold <= 0 {
+			d.Threshold = 4
+		}
+		if d.MinCount == 0 {
+			d.MinCount = 10
+		}
+		if d.MinSamples == 0 {
+			d.MinSamples = 30
+		}
+		if d.MaxKeys <= 0 {
+			d.MaxKeys = 10000
+		}
+		d.seconds = uint32(d.Interval / time.Second)
+		if d.baselines == nil {
+			d.baselines = make(map[key]*baseline)
+			d.lru = list.New()
+		}
+	})
+}
+
+// count an error for its service and its method
+func (d *Detector) Add(pl *pb.ProtoLog) {
+	if pl.Err == nil {
+		return
+	}
+	d.init()
+	ts := pl.Received
+	if ts == 0 {
+		ts = pl.Err.Timestamp
+	}
+	keys := []key{{service: pl.Err.ServiceName}}
+	if pl.Err.MethodName != "" {
+		keys = append(keys, key{service: pl.Err.ServiceName, method: pl.Err.MethodName})
+	}
+	var anomalies []*pb.Anomaly
+	d.lock.Lock()
+	for _, k := range keys {
+		b := d.get(k, ts)
+		b.roll(d, ts)
+		b.count++
+		if ts >= b.last_seen {
+			b.last_seen = ts
+			d.lru.MoveToFront(b.el)
+		}
+		if !b.active && d.isAnomaly(b) {
+			b.active = true
+			b.started = ts
+			anomalies = append(anomalies, d.anomaly(k, b))
+		}
+	}
+	d.lock.Unlock()
+	if d.OnAnomaly == nil {
+		return
+	}
+	for _, a := range anomalies {
+		d.OnAnomaly(a)
+	}
+}
+
+// close the intervals which ended before now, also of services and methods without errors since
+func (d *Detector) Tick(now uint32) {
+	d.init()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for _, b := range d.baselines {
+		b.roll(d, now)
+	}
+}
+
+// the baseline of a key, created if necessary. must be called with lock held
+func (d *Detector) get(k key, ts uint32) *baseline {
+	b := d.baselines[k]
+	if b != nil {
+		return b
+	}
+	for len(d.baselines) >= d.MaxKeys {
+		oldest := d.lru.Remove(d.lru.Back()).(key)
+		delete(d.baselines, oldest)
+	}
+	b = &baseline{interval: ts - ts%d.seconds, last_seen: ts}
+	d.baselines[k] = b
+	b.el = d.lru.PushFront(k)
+	return b
+}
+
+// must be called with lock held
+func (d *Detector) isAnomaly(b *baseline) bool {
+	return b.samples >= d.MinSamples && b.count >= d.MinCount && b.score() >= d.Threshold
+}
+
+// update the baseline with the intervals which ended before ts. An anomaly stays active into the new interval if the
+// interval which ended was anomalous, and ends otherwise
+func (b *baseline) roll(d *Detector, ts uint32) {
+	current := ts - ts%d.seconds
+	if current <= b.interval {
+		return
+	}
+	b.active = d.isAnomaly(b)
+	intervals := (current - b.interval) / d.seconds
+	b.update(d.Alpha, float64(b.count))
+	if intervals-1 > max_idle_intervals {
+		b.mean = 0
+		b.variance = 0
+		b.samples += intervals - 1
+	} else {
+		for i := uint32(1); i < intervals; i++ {
+			b.update(d.Alpha, 0)
+		}
+	}
+	b.interval = current
+	b.count = 0
+}
+
+// add the count of an interval to the moving average and variance
+func (b *baseline) update(alpha, x float64) {
+	if b.samples == 0 {
+		b.mean = x
+		b.samples = 1
+		return
+	}
+	diff := x - b.mean
+	incr := alpha * diff
+	b.mean += incr
+	b.variance = (1 - alpha) * (b.variance + diff*incr)
+	b.samples++
+}
+
+func (b *baseline) deviation() float64 {
+	return math.Sqrt(b.variance)
+}
+
+// deviations the current count is above the baseline. errors are random, so the deviation is at least that of a
+// poisson process with the baseline's rate (and at least 1), even if the past counts happen to be very steady
+func (b *baseline) score() float64 {
+	dev := math.Max(b.deviation(), math.Max(math.Sqrt(b.mean), 1))
+	return (float64(b.count) - b.mean) / dev
+}
+
+// must be called with lock held
+func (d *Detector) anomaly(k key, b *baseline) *pb.Anomaly {
+	return &pb.Anomaly{
+		ServiceName:     k.service,
+		MethodName:      k.method,
+		Active:          b.active,
+		Baseline:        b.mean,
+		Deviation:       b.deviation(),
+		Count:           b.count,
+		Score:           b.score(),
+		Started:         b.started,
+		Samples:         b.samples,
+		Interval:        b.interval,
+		IntervalSeconds: d.seconds,
+	}
+}
+
+// the active anomalies (or all baselines with req.All) matching the request, highest score first
+func (d *Detector) List(req *pb.GetAnomaliesRequest) []*pb.Anomaly {
+	d.init()
+	var services []string
+	for _, s := range req.Services {
+		services = append(services, strings.ToLower(s))
+	}
+	var res []*pb.Anomaly
+	d.lock.Lock()
+	for k, b := range d.baselines {
+		if !req.All && !b.active {
+			continue
+		}
+		if len(services) > 0 && !containsAny(strings.ToLower(k.service), services) {
+			continue
+		}
+		res = append(res, d.anomaly(k, b))
+	}
+	d.lock.Unlock()
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.ServiceName != b.ServiceName {
+			return a.ServiceName < b.ServiceName
+		}
+		return a.MethodName < b.MethodName
+	})
+	return res
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// write all baselines to a file
+func (d *Detector) Save(filename string) error {
+	al := &pb.AnomalyList{Anomalies: d.List(&pb.GetAnomaliesRequest{All: true})}
+	bs, err := utils.MarshalBytes(al)
+	if err != nil {
+		return err
+	}
+	tmpname := filename + ".tmp"
+	err = utils.WriteFile(tmpname, bs)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpname, filename)
+}
+
+// read baselines saved with Save(). a missing file is not an error.
+// baselines saved with a different interval are not loaded
+func (d *Detector) Load(filename string) error {
+	d.init()
+	if !utils.FileExists(filename) {
+		return nil
+	}
+	bs, err := utils.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	al := &pb.AnomalyList{}
+	err = utils.UnmarshalBytes(bs, al)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %s", filename, err)
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.baselines = make(map[key]*baseline)
+	d.lru = list.New()
+	sort.Slice(al.Anomalies, func(i, j int) bool {
+		return al.Anomalies[i].Interval < al.Anomalies[j].Interval
+	})
+	for _, a := range al.Anomalies {
+		if a.IntervalSeconds != d.seconds {
+			continue
+		}
+		k := key{service: a.ServiceName, method: a.MethodName}
+		b := &baseline{
+			mean:      a.Baseline,
+			variance:  a.Deviation * a.Deviation,
+			samples:   a.Samples,
+			interval:  a.Interval,
+			count:     a.Count,
+			active:    a.Active,
+			started:   a.Started,
+			last_seen: a.Interval,
+		}
+		d.baselines[k] = b
+		b.el = d.lru.PushFront(k)
+	}
+	return nil
+}
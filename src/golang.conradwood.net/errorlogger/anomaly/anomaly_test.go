@@ -0,0 +1,137 @@
+package anomaly
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+)
+
+func newLog(service, method string, ts uint32) *pb.ProtoLog {
+	return &pb.ProtoLog{Err: &pb.ErrorLogRequest{ServiceName: service, MethodName: method}, Received: ts}
+}
+
+// add n errors per interval for the given number of intervals, starting at interval "start"
+func feed(d *Detector, service, method string, start, intervals uint32, n int) {
+	for i := uint32(0); i < intervals; i++ {
+		ts := (start + i) * 60
+		for j := 0; j < n; j++ {
+			d.Add(newLog(service, method, ts+uint32(j%60)))
+		}
+	}
+}
+
+func TestDetector(t *testing.T) {
+	var anomalies []*pb.Anomaly
+	d := &Detector{Interval: time.Minute, MinSamples: 10, OnAnomaly: func(a *pb.Anomaly) { anomalies = append(anomalies, a) }}
+	// baselines of 5 and 100 errors per minute
+	feed(d, "quiet.Service", "Get", 0, 20, 5)
+	feed(d, "busy.Service", "Get", 0, 20, 100)
+	d.Tick(20 * 60)
+	if len(anomalies) != 0 {
+		t.Fatalf("anomalies at baseline: %v", anomalies)
+	}
+	all := d.List(&pb.GetAnomaliesRequest{All: true, Services: []string{"quiet"}})
+	if len(all) != 2 || all[0].Samples != 20 || all[0].Baseline < 4.9 || all[0].Baseline > 5.1 {
+		t.Fatalf("wrong baselines: %v", all)
+	}
+
+	// 20 errors per minute is a spike for the quiet service, 120 is not for the busy one
+	feed(d, "busy.Service", "Get", 20, 1, 120)
+	feed(d, "quiet.Service", "Get", 20, 1, 20)
+	if len(anomalies) != 2 || anomalies[0].ServiceName != "quiet.Service" || anomalies[0].Count < 10 {
+		t.Fatalf("expected anomalies for quiet.Service and quiet.Service/Get, got %v", anomalies)
+	}
+	methods := 0
+	for _, a := range anomalies {
+		if a.MethodName == "Get" {
+			methods++
+		}
+	}
+	if methods != 1 {
+		t.Errorf("expected one anomaly per service and method, got %v", anomalies)
+	}
+	active := d.List(&pb.GetAnomaliesRequest{})
+	if len(active) != 2 || !active[0].Active || active[0].ServiceName != "quiet.Service" {
+		t.Errorf("wrong active anomalies: %v", active)
+	}
+	// not raised again while active
+	feed(d, "quiet.Service", "Get", 21, 1, 20)
+	if len(anomalies) != 2 {
+		t.Errorf("anomaly raised again: %v", anomalies[2:])
+	}
+
+	// back to normal. Until the normal interval is complete, the spike may go on
+	feed(d, "quiet.Service", "Get", 22, 1, 5)
+	d.Tick(22*60 + 59)
+	if active := d.List(&pb.GetAnomaliesRequest{}); len(active) != 2 {
+		t.Errorf("anomalies ended before the interval is complete: %v", active)
+	}
+	d.Tick(23 * 60)
+	if active := d.List(&pb.GetAnomaliesRequest{}); len(active) != 0 {
+		t.Errorf("anomalies still active: %v", active)
+	}
+	// a spike which ends within its interval, active until the following interval is complete
+	feed(d, "quiet.Service", "Get", 23, 1, 20)
+	d.Tick(24 * 60)
+	if active := d.List(&pb.GetAnomaliesRequest{}); len(active) != 2 {
+		t.Errorf("anomalies ended with the interval of the spike: %v", active)
+	}
+	d.Tick(25 * 60)
+	if active := d.List(&pb.GetAnomaliesRequest{}); len(active) != 0 {
+		t.Errorf("anomalies still active after a normal interval: %v", active)
+	}
+
+	// baselines persist
+	fname := filepath.Join(t.TempDir(), "baselines.pb")
+	err := d.Save(fname)
+	if err != nil {
+		t.Fatalf("failed to save: %s", err)
+	}
+	d2 := &Detector{Interval: time.Minute, MinSamples: 10, OnAnomaly: func(a *pb.Anomaly) { anomalies = append(anomalies, a) }}
+	err = d2.Load(fname)
+	if err != nil {
+		t.Fatalf("failed to load: %s", err)
+	}
+	all = d2.List(&pb.GetAnomaliesRequest{All: true})
+	if len(all) != 4 {
+		t.Fatalf("expected 4 baselines after loading, got %v", all)
+	}
+	anomalies = nil
+	feed(d2, "quiet.Service", "Get", 25, 1, 25)
+	if len(anomalies) != 2 {
+		t.Errorf("expected anomalies with loaded baselines, got %v", anomalies)
+	}
+	d3 := &Detector{Interval: time.Hour}
+	d3.Load(fname)
+	if len(d3.List(&pb.GetAnomaliesRequest{All: true})) != 0 {
+		t.Errorf("loaded baselines of a different interval")
+	}
+}
+
+func TestIdle(t *testing.T) {
+	d := &Detector{MinSamples: 5, MaxKeys: 2}
+	feed(d, "a", "", 0, 10, 50)
+	all := d.List(&pb.GetAnomaliesRequest{All: true, Services: []string{"a"}})
+	if len(all) != 1 || all[0].Count != 50 {
+		t.Fatalf("expected one baseline without method: %v", all)
+	}
+	d.Tick(1000000 * 60) // far beyond max_idle_intervals
+	all = d.List(&pb.GetAnomaliesRequest{All: true, Services: []string{"a"}})
+	if len(all) != 1 || all[0].Baseline != 0 {
+		t.Errorf("expected baseline to decay to 0: %v", all)
+	}
+	// bounded
+	feed(d, "b", "", 1000001, 1, 1)
+	if n := len(d.List(&pb.GetAnomaliesRequest{All: true})); n != 2 {
+		t.Errorf("expected 2 baselines, got %d", n)
+	}
+	// the least recently seen is forgotten
+	feed(d, "a", "", 1000002, 1, 1)
+	feed(d, "c", "", 1000003, 1, 1)
+	all = d.List(&pb.GetAnomaliesRequest{All: true})
+	if len(all) != 2 || len(d.List(&pb.GetAnomaliesRequest{All: true, Services: []string{"b"}})) != 0 {
+		t.Errorf("expected b to be forgotten: %v", all)
+	}
+}
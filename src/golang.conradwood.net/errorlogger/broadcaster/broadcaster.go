@@ -2,55 +2,103 @@
 sometimes we have clients connecting to a single server to "listen" to new events, this broadcaster helps to implement that.
 a target may register itself to listen for new data
 the producer calls NewData when it has new data
+a listener which falls behind by more than QueueSize items is not sent any more data. Its Handle() returns ErrOverflow once
+it processed what was queued, rather than silently skipping data
 */
 package broadcaster
 
 import (
-	"fmt"
+	"errors"
 	"io"
 	"sync"
 )
 
+var (
+	ErrOverflow = errors.New("listener fell behind, data dropped")
+)
+
 type Broadcaster struct {
-	listeners []*broadcastListener
-	lock      sync.Mutex
+	listeners  []*Listener
+	lock       sync.Mutex
+	QueueSize  int    // how many items to queue per listener before it overflows. defaults to 10
+	OnOverflow func() // optional, called when a listener overflows. must not block
 }
 
 func (b *Broadcaster) NewData(data any) {
+	b.lock.Lock()
 	x := b.listeners
+	b.lock.Unlock()
 	for _, bl := range x {
+		select {
+		case <-bl.overflow:
+			// data after the gap would be misleading
+			continue
+		default:
+		}
 		select {
 		case bl.ch <- data:
 			//
 		default:
-			fmt.Printf("[broadcaster] failed to send to listener\n")
+			bl.once.Do(func() {
+				close(bl.overflow)
+				if b.OnOverflow != nil {
+					b.OnOverflow()
+				}
+			})
 		}
 	}
 }
 
+// register a listener and process data with f until f returns an error
 func (b *Broadcaster) Handle(i any, f func(target any, data any) error) error {
-	bl := &broadcastListener{srv: i, f: f, ch: make(chan any, 10)}
+	return b.Register().Handle(i, f)
+}
+
+// register a listener. it queues data from now on, but does not process it until Handle() is called on it.
+// the caller must either call Handle() or Close() on the listener
+func (b *Broadcaster) Register() *Listener {
+	qs := b.QueueSize
+	if qs == 0 {
+		qs = 10
+	}
+	bl := &Listener{b: b, ch: make(chan any, qs), overflow: make(chan struct{})}
 	b.lock.Lock()
 	b.listeners = append(b.listeners, bl)
 	b.lock.Unlock()
+	return bl
+}
+
+type Listener struct {
+	b        *Broadcaster
+	ch       chan any
+	overflow chan struct{} // closed once data was dropped
+	once     sync.Once
+}
+
+// process queued and new data with f until f returns an error. the listener is removed from the broadcaster afterwards.
+// Returns ErrOverflow after the data queued before an overflow
+func (bl *Listener) Handle(i any, f func(target any, data any) error) error {
 	var err error
 	for {
-		data := <-bl.ch
-		err := bl.f(bl.srv, data)
+		var data any
+		select {
+		case data = <-bl.ch:
+		case <-bl.overflow:
+			select {
+			case data = <-bl.ch:
+			default:
+				err = ErrOverflow
+			}
+		}
 		if err != nil {
 			break
 		}
-	}
-	b.lock.Lock()
-	var n []*broadcastListener
-	for _, blx := range b.listeners {
-		if bl == blx {
-			continue
+		err = f(i, data)
+		if err != nil {
+			break
 		}
-		n = append(n, blx)
 	}
-	b.listeners = n
-	b.lock.Unlock()
+	bl.Close()
 
 	if err == io.EOF {
 		return nil
@@ -59,8 +107,17 @@ func (b *Broadcaster) Handle(i any, f func(target any, data any) error) error {
 
 }
 
-type broadcastListener struct {
-	srv any
-	f   func(target any, data any) error
-	ch  chan any
+// remove the listener from the broadcaster
+func (bl *Listener) Close() {
+	b := bl.b
+	b.lock.Lock()
+	var n []*Listener
+	for _, blx := range b.listeners {
+		if bl == blx {
+			continue
+		}
+		n = append(n, blx)
+	}
+	b.listeners = n
+	b.lock.Unlock()
 }
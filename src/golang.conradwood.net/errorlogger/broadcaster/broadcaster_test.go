@@ -0,0 +1,32 @@
+package broadcaster
+
+import (
+	"testing"
+)
+
+func TestOverflow(t *testing.T) {
+	overflows := 0
+	b := &Broadcaster{QueueSize: 3, OnOverflow: func() { overflows++ }}
+	bl := b.Register()
+	// the listener does not handle data yet, e.g. while a history is replayed
+	for i := 0; i < 5; i++ {
+		b.NewData(i)
+	}
+	if overflows != 1 {
+		t.Errorf("expected 1 overflow, got %d", overflows)
+	}
+	var got []int
+	err := bl.Handle(nil, func(target any, data any) error {
+		got = append(got, data.(int))
+		return nil
+	})
+	if err != ErrOverflow {
+		t.Errorf("expected ErrOverflow, got %v", err)
+	}
+	if len(got) != 3 || got[0] != 0 || got[2] != 2 {
+		t.Errorf("expected the data queued before the overflow, got %v", got)
+	}
+	if len(b.listeners) != 0 {
+		t.Errorf("listener not removed after overflow")
+	}
+}
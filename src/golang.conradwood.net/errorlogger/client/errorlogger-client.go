@@ -3,11 +3,13 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
-	_ "golang.conradwood.net/apis/common"
+	"golang.conradwood.net/apis/common"
 	pb "golang.conradwood.net/apis/errorlogger"
 	"golang.conradwood.net/go-easyops/auth"
 	"golang.conradwood.net/go-easyops/authremote"
@@ -18,11 +20,56 @@ var (
 	echoClient pb.ErrorLoggerClient
 	sn         = flag.String("service", "", "service name to filter on")
 	listen     = flag.Bool("listen", false, "listen for errors in realtime")
+	users      = flag.String("user", "", "comma delimited list of userids to filter on")
+	methods    = flag.String("method", "", "comma delimited list of method names to filter on")
+	errcodes   = flag.String("code", "", "comma delimited list of grpc error codes to filter on")
+	callers    = flag.String("calling_service", "", "comma delimited list of calling service ids or emails to filter on")
+	requestid  = flag.String("requestid", "", "requestid to filter on")
+	text       = flag.String("text", "", "text to search for in log and error messages")
+	teams      = flag.String("team", "", "comma delimited list of teams to filter on")
+	since      = flag.Duration("since", 0, "if set, print the logs received between this long ago and -until and exit")
+	until      = flag.Duration("until", 0, "with -since, print logs received up to this long ago")
+	logs       = flag.Int("logs", 0, "number of historic logs to show before listening in realtime (0 = server default)")
+	groups     = flag.Bool("groups", false, "list error groups (most recent first) and exit")
+	by_count   = flag.Bool("by_count", false, "with -groups, list the most frequent groups first")
+	limit      = flag.Int("limit", 0, "with -groups, number of groups to list (0 = server default)")
+	states     = flag.String("state", "", "with -groups, comma delimited list of states to list (e.g. open,regressed)")
+	set_state  = flag.String("set_state", "", "set the state of the group given by -fingerprint to open, acknowledged, resolved or muted and exit")
+	fp         = flag.String("fingerprint", "", "with -set_state, the fingerprint (or a unique prefix of it) of the group")
+	mute_for   = flag.Duration("mute_for", 0, "with -set_state=muted, unmute after this long")
+	mute_count = flag.Uint("mute_count", 0, "with -set_state=muted, unmute after this many more errors")
+	muted      = flag.Bool("include_muted", false, "with -listen, also show errors of muted groups")
+	show_stats = flag.Bool("stats", false, "print error counts between -since (default 1h) and -until and exit")
+	group_by   = flag.String("group_by", "service,method", "with -stats, comma delimited list of fields to count by: service, method, code, user, calling_service, team")
+	top        = flag.Int("top", 0, "with -stats, only print the N largest counts")
+	alerts     = flag.Bool("alerts", false, "print the state of the alert rules and exit")
+	anomalies  = flag.Bool("anomalies", false, "print services and methods whose error rate is above their baseline and exit")
+	baselines  = flag.Bool("baselines", false, "with -anomalies, print all baselines")
 )
 
 func main() {
 	flag.Parse()
-	if *listen {
+	if *groups {
+		utils.Bail("failed to list groups", ListGroups())
+		os.Exit(0)
+	}
+	if *anomalies {
+		utils.Bail("failed to get anomalies", Anomalies())
+		os.Exit(0)
+	}
+	if *alerts {
+		utils.Bail("failed to get alerts", Alerts())
+		os.Exit(0)
+	}
+	if *show_stats {
+		utils.Bail("failed to get stats", Stats())
+		os.Exit(0)
+	}
+	if *set_state != "" {
+		utils.Bail("failed to set state", SetState())
+		os.Exit(0)
+	}
+	if *listen || *since != 0 {
 		utils.Bail("failed to listen", Listen())
 		os.Exit(0)
 	}
@@ -40,10 +87,26 @@ func main() {
 }
 
 func getServiceNames() []string {
-	if *sn == "" {
+	return splitList(*sn)
+}
+
+func getErrorCodes() ([]uint32, error) {
+	var res []uint32
+	for _, s := range splitList(*errcodes) {
+		c, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid error code \"%s\": %s", s, err)
+		}
+		res = append(res, uint32(c))
+	}
+	return res, nil
+}
+
+func splitList(sl string) []string {
+	if sl == "" {
 		return nil
 	}
-	svs := strings.Split(*sn, ",")
+	svs := strings.Split(sl, ",")
 	for i, s := range svs {
 		s = strings.Trim(s, " ")
 		svs[i] = s
@@ -51,9 +114,27 @@ func getServiceNames() []string {
 	return svs
 }
 func Listen() error {
+	codes, err := getErrorCodes()
+	if err != nil {
+		return err
+	}
 	ctx := authremote.ContextWithTimeout(time.Duration(60) * time.Minute)
 	rlr := &pb.ReadLogRequest{
-		Services: getServiceNames(),
+		Services:        getServiceNames(),
+		LogsToSend:      uint32(*logs),
+		UserIDs:         splitList(*users),
+		Methods:         splitList(*methods),
+		ErrorCodes:      codes,
+		CallingServices: splitList(*callers),
+		RequestID:       *requestid,
+		Text:            *text,
+		IncludeMuted:    *muted,
+		Teams:           splitList(*teams),
+	}
+	if *since != 0 {
+		now := time.Now()
+		rlr.StartTimestamp = uint32(now.Add(-*since).Unix())
+		rlr.EndTimestamp = uint32(now.Add(-*until).Unix())
 	}
 
 	srv, err := pb.GetErrorLoggerClient().ReadLog(ctx, rlr)
@@ -63,6 +144,9 @@ func Listen() error {
 	fmt.Printf("Listening for services \"%s\"...\n", strings.Join(rlr.Services, " "))
 	for {
 		r, err := srv.Recv()
+		if err == io.EOF && rlr.EndTimestamp != 0 {
+			return nil
+		}
 		if err != nil {
 			return err
 		}
@@ -75,6 +159,139 @@ func Listen() error {
 	}
 }
 
+func ListGroups() error {
+	req := &pb.ListErrorGroupsRequest{
+		Limit:    uint32(*limit),
+		Services: getServiceNames(),
+	}
+	for _, s := range splitList(*states) {
+		st, err := parseState(s)
+		if err != nil {
+			return err
+		}
+		req.States = append(req.States, st)
+	}
+	if *by_count {
+		req.Order = pb.GroupOrder_BY_COUNT
+	}
+	egl, err := pb.GetErrorLoggerClient().ListErrorGroups(authremote.Context(), req)
+	if err != nil {
+		return err
+	}
+	for _, g := range egl.Groups {
+		fmt.Printf("%s %8d %5d %s %s %-12s %d %s\n", utils.TimestampString(g.LastSeen), g.Count, len(g.UserIDs), strlen(g.ServiceName+"/"+g.MethodName, 50), g.Fingerprint[:8], g.State, g.ErrorCode, g.Message)
+	}
+	return nil
+}
+
+func SetState() error {
+	st, err := parseState(*set_state)
+	if err != nil {
+		return err
+	}
+	req := &pb.SetGroupStateRequest{
+		Fingerprint:     *fp,
+		State:           st,
+		MuteOccurrences: uint32(*mute_count),
+	}
+	if *mute_for != 0 {
+		req.MuteUntil = uint32(time.Now().Add(*mute_for).Unix())
+	}
+	g, err := pb.GetErrorLoggerClient().SetGroupState(authremote.Context(), req)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Group %s (%s/%s) is now %s\n", g.Fingerprint, g.ServiceName, g.MethodName, g.State)
+	return nil
+}
+
+func Stats() error {
+	req := &pb.StatsRequest{TopN: uint32(*top)}
+	for _, s := range splitList(*group_by) {
+		f, ok := pb.StatsField_value[strings.ToUpper(s)]
+		if !ok {
+			return fmt.Errorf("invalid field \"%s\"", s)
+		}
+		req.GroupBy = append(req.GroupBy, pb.StatsField(f))
+	}
+	now := time.Now()
+	if *since != 0 {
+		req.StartTimestamp = uint32(now.Add(-*since).Unix())
+	}
+	if *until != 0 {
+		req.EndTimestamp = uint32(now.Add(-*until).Unix())
+	}
+	sr, err := pb.GetErrorLoggerClient().GetStats(authremote.Context(), req)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d errors from %s to %s\n", sr.Total, utils.TimestampString(sr.StartTimestamp), utils.TimestampString(sr.EndTimestamp))
+	for _, e := range sr.Entries {
+		var fields []string
+		for _, f := range req.GroupBy {
+			switch f {
+			case pb.StatsField_SERVICE:
+				fields = append(fields, e.ServiceName)
+			case pb.StatsField_METHOD:
+				fields = append(fields, e.MethodName)
+			case pb.StatsField_CODE:
+				fields = append(fields, strconv.Itoa(int(e.ErrorCode)))
+			case pb.StatsField_USER:
+				fields = append(fields, e.UserID)
+			case pb.StatsField_CALLING_SERVICE:
+				fields = append(fields, e.CallingService)
+			case pb.StatsField_TEAM:
+				fields = append(fields, e.Team)
+			}
+		}
+		fmt.Printf("%8d %s\n", e.Count, strings.Join(fields, " "))
+	}
+	return nil
+}
+
+func Alerts() error {
+	al, err := pb.GetErrorLoggerClient().GetAlerts(authremote.Context(), &common.Void{})
+	if err != nil {
+		return err
+	}
+	for _, r := range al.Rules {
+		st := "ok"
+		if r.Firing {
+			st = "FIRING"
+		}
+		last := "never"
+		if r.LastAlert != nil {
+			last = utils.TimestampString(r.LastAlert.Timestamp)
+		}
+		fmt.Printf("%s %-6s %6d in window, fired %d times, last %s: %s\n", strlen(r.Rule, 30), st, r.Count, r.Fired, last, r.Description)
+	}
+	return nil
+}
+
+func Anomalies() error {
+	req := &pb.GetAnomaliesRequest{All: *baselines, Services: getServiceNames()}
+	al, err := pb.GetErrorLoggerClient().GetAnomalies(authremote.Context(), req)
+	if err != nil {
+		return err
+	}
+	for _, a := range al.Anomalies {
+		st := "ok"
+		if a.Active {
+			st = "SPIKE"
+		}
+		fmt.Printf("%s %-5s %6d errors, baseline %8.1f±%-6.1f score %6.1f\n", strlen(a.ServiceName+"/"+a.MethodName, 50), st, a.Count, a.Baseline, a.Deviation, a.Score)
+	}
+	return nil
+}
+
+func parseState(s string) (pb.GroupState, error) {
+	st, ok := pb.GroupState_value[strings.ToUpper(s)]
+	if !ok {
+		return 0, fmt.Errorf("invalid state \"%s\"", s)
+	}
+	return pb.GroupState(st), nil
+}
+
 func strlen(s string, ln int) string {
 	if len(s) > ln {
 		return s[:ln-3] + "..."
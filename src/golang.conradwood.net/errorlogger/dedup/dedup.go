@@ -0,0 +1,86 @@
+/*
+a bounded set of recently seen keys, used to recognise retried requests.
+keys are forgotten after a time window, or earlier if the set is full.
+*/
+package dedup
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+type Set struct {
+	Window     time.Duration // how long keys are remembered. defaults to 10 minutes
+	MaxEntries int           // the oldest keys are forgotten if there are more than this. defaults to 100000
+	lock       sync.Mutex
+	start      sync.Once
+	seen       map[string]*list.Element
+	order      *list.List // oldest first
+	now        func() time.Time
+}
+
+type key struct {
+	key  string
+	seen time.Time
+}
+
+func (s *Set) init() {
+	s.start.Do(func() {
+		if s.Window <= 0 {
+			s.Window = 10 * time.Minute
+		}
+		if s.MaxEntries <= 0 {
+			s.MaxEntries = 100000
+		}
+		if s.now == nil {
+			s.now = time.Now
+		}
+		s.seen = make(map[string]*list.Element)
+		s.order = list.New()
+	})
+}
+
+// true if k was seen within the window. otherwise k is remembered and false is returned
+func (s *Set) Seen(k string) bool {
+	s.init()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := s.now()
+	for el := s.order.Front(); el != nil && now.Sub(el.Value.(*key).seen) > s.Window; el = s.order.Front() {
+		s.remove(el)
+	}
+	if s.seen[k] != nil {
+		return true
+	}
+	s.seen[k] = s.order.PushBack(&key{key: k, seen: now})
+	for s.order.Len() > s.MaxEntries {
+		s.remove(s.order.Front())
+	}
+	return false
+}
+
+// forget k, e.g. because the request it was seen with failed and may be retried
+func (s *Set) Forget(k string) {
+	s.init()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	el := s.seen[k]
+	if el != nil {
+		s.remove(el)
+	}
+}
+
+// the number of keys remembered
+func (s *Set) Len() int {
+	s.init()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.order.Len()
+}
+
+// must be called with lock held
+func (s *Set) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.seen, el.Value.(*key).key)
+}
@@ -0,0 +1,47 @@
+package dedup
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSet(t *testing.T) {
+	now := time.Now()
+	s := &Set{Window: time.Minute, MaxEntries: 3, now: func() time.Time { return now }}
+	if s.Seen("a") {
+		t.Errorf("new key reported as seen")
+	}
+	if !s.Seen("a") {
+		t.Errorf("key not reported as seen")
+	}
+	now = now.Add(30 * time.Second)
+	s.Seen("b")
+	now = now.Add(40 * time.Second)
+	// "a" is outside the window, "b" is not
+	if s.Seen("a") {
+		t.Errorf("key reported as seen after window")
+	}
+	if !s.Seen("b") {
+		t.Errorf("key within window not reported as seen")
+	}
+
+	s.Forget("b")
+	if s.Seen("b") {
+		t.Errorf("forgotten key reported as seen")
+	}
+
+	// bounded
+	for i := 0; i < 10; i++ {
+		s.Seen(fmt.Sprintf("k%d", i))
+	}
+	if s.Len() != 3 {
+		t.Errorf("expected 3 keys, got %d", s.Len())
+	}
+	if s.Seen("k0") {
+		t.Errorf("oldest key not forgotten")
+	}
+	if !s.Seen("k9") {
+		t.Errorf("newest key forgotten")
+	}
+}
@@ -0,0 +1,153 @@
+/*
+summaries of the errors of a time window, one per owner of services (see Owner), sent by email
+*/
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/template"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/errorlogger/stats"
+	"golang.conradwood.net/go-easyops/utils"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	max_counts = 20 // method/code combinations per service
+	max_users  = 5  // top affected users per service
+	max_groups = 20 // new groups per service
+)
+
+// collects the errors of a time window
+type Builder struct {
+	owners   []*Owner
+	start    uint32
+	end      uint32
+	services map[string]*Service
+}
+
+// the errors of one service
+type Service struct {
+	Name      string
+	Total     uint64
+	Counts    []*pb.StatsEntry // by method and code, largest first
+	Users     []*pb.StatsEntry // most affected users first
+	NewGroups []*pb.ErrorGroup // groups first seen in the window
+	counts    *stats.Aggregator
+	users     *stats.Aggregator
+}
+
+// the digest for one recipient
+type Digest struct {
+	Recipient string
+	Start     uint32
+	End       uint32
+	Services  []*Service // the services the recipient owns which had errors, by name
+}
+
+func NewBuilder(owners []*Owner, start, end uint32) *Builder {
+	return &Builder{owners: owners, start: start, end: end, services: make(map[string]*Service)}
+}
+
+// add an error received within the window
+func (b *Builder) Add(pl *pb.ProtoLog) {
+	if pl.Err == nil {
+		return
+	}
+	s := b.service(pl.Err.ServiceName)
+	s.Total++
+	s.counts.Add(pl)
+	if pl.Err.UserID != "" {
+		s.users.Add(pl)
+	}
+}
+
+// add groups. those first seen within the window are reported as new
+func (b *Builder) AddGroups(groups []*pb.ErrorGroup) {
+	for _, g := range groups {
+		if g.FirstSeen < b.start || g.FirstSeen > b.end {
+			continue
+		}
+		s := b.service(g.ServiceName)
+		s.NewGroups = append(s.NewGroups, g)
+	}
+}
+
+func (b *Builder) service(name string) *Service {
+	s := b.services[name]
+	if s == nil {
+		s = &Service{
+			Name:   name,
+			counts: stats.NewAggregator([]pb.StatsField{pb.StatsField_METHOD, pb.StatsField_CODE}),
+			users:  stats.NewAggregator([]pb.StatsField{pb.StatsField_USER}),
+		}
+		b.services[name] = s
+	}
+	return s
+}
+
+// one digest per recipient, sorted by recipient. services without owners are not included
+func (b *Builder) Digests() []*Digest {
+	digests := make(map[string]*Digest)
+	for _, s := range b.services {
+		s.Counts = s.counts.Entries(max_counts)
+		s.Users = s.users.Entries(max_users)
+		sort.Slice(s.NewGroups, func(i, j int) bool { return s.NewGroups[i].Count > s.NewGroups[j].Count })
+		if len(s.NewGroups) > max_groups {
+			s.NewGroups = s.NewGroups[:max_groups]
+		}
+		for _, r := range Recipients(b.owners, s.Name) {
+			d := digests[r]
+			if d == nil {
+				d = &Digest{Recipient: r, Start: b.start, End: b.end}
+				digests[r] = d
+			}
+			d.Services = append(d.Services, s)
+		}
+	}
+	var res []*Digest
+	for _, d := range digests {
+		sort.Slice(d.Services, func(i, j int) bool { return d.Services[i].Name < d.Services[j].Name })
+		res = append(res, d)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Recipient < res[j].Recipient })
+	return res
+}
+
+func (d *Digest) Subject() string {
+	var total uint64
+	for _, s := range d.Services {
+		total += s.Total
+	}
+	return fmt.Sprintf("Errors from %s to %s: %d errors in %d services", utils.TimestampString(d.Start), utils.TimestampString(d.End), total, len(d.Services))
+}
+
+var digestTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"code": func(c uint32) string { return codes.Code(c).String() },
+	"time": utils.TimestampString,
+}).Parse(`Errors from {{time .Start}} to {{time .End}}
+{{range .Services}}
+== {{.Name}}: {{.Total}} errors
+{{if .Counts}}
+By method and code:
+{{range .Counts}}  {{printf "%8d" .Count}} {{.MethodName}} {{code .ErrorCode}}
+{{end}}{{end}}{{if .NewGroups}}
+New error groups:
+{{range .NewGroups}}  {{printf "%8d" .Count}} {{.MethodName}} {{code .ErrorCode}} {{.Message}} ({{printf "%.8s" .Fingerprint}})
+{{end}}{{end}}{{if .Users}}
+Most affected users:
+{{range .Users}}  {{printf "%8d" .Count}} {{.UserID}}
+{{end}}{{end}}{{end}}`))
+
+// the text of the email
+func (d *Digest) Render() (string, error) {
+	var buf bytes.Buffer
+	err := digestTemplate.Execute(&buf, d)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
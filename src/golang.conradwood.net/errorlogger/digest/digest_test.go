@@ -0,0 +1,189 @@
+package digest
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+)
+
+const test_owners = `
+owners:
+  - services: ["foo.*"]
+    emails: [foo@example.com, all@example.com]
+  - services: ["bar.BarService"]
+    emails: [all@example.com]
+`
+
+// a minimal SMTP server which accepts all mails
+type fakeSMTP struct {
+	l     net.Listener
+	lock  sync.Mutex
+	mails []*fakeMail
+}
+
+type fakeMail struct {
+	from string
+	to   []string
+	data string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err)
+	}
+	f := &fakeSMTP{l: l}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+	return f
+}
+
+func (f *fakeSMTP) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	reply := func(s string) { c.Write([]byte(s + "\r\n")) }
+	reply("220 localhost ESMTP")
+	m := &fakeMail{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			m.from = strings.Trim(line[10:], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			m.to = append(m.to, strings.Trim(line[8:], "<>"))
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			m.data = data.String()
+			f.lock.Lock()
+			f.mails = append(f.mails, m)
+			f.lock.Unlock()
+			m = &fakeMail{}
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func newLog(service, method string, code uint32, user string, ts uint32) *pb.ProtoLog {
+	return &pb.ProtoLog{
+		Err:      &pb.ErrorLogRequest{ServiceName: service, MethodName: method, ErrorCode: code, UserID: user},
+		Received: ts,
+	}
+}
+
+func TestOwners(t *testing.T) {
+	owners, err := ParseOwners([]byte(test_owners))
+	if err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+	for service, expected := range map[string]string{
+		"foo.FooService": "all@example.com foo@example.com",
+		"bar.BarService": "all@example.com",
+		"BAR.barservice": "all@example.com",
+		"bar.Other":      "",
+	} {
+		got := strings.Join(Recipients(owners, service), " ")
+		if got != expected {
+			t.Errorf("%s: expected recipients \"%s\", got \"%s\"", service, expected, got)
+		}
+	}
+	for _, bad := range []string{
+		"owners:\n  - services: [foo]\n",
+		"owners:\n  - services: [\"[\"]\n    emails: [a@example.com]\n",
+	} {
+		_, err := ParseOwners([]byte(bad))
+		if err == nil {
+			t.Errorf("invalid owners accepted: %s", bad)
+		}
+	}
+}
+
+func TestDigest(t *testing.T) {
+	owners, err := ParseOwners([]byte(test_owners))
+	if err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+	b := NewBuilder(owners, 1000, 2000)
+	b.Add(newLog("foo.FooService", "Get", 5, "1", 1100))
+	b.Add(newLog("foo.FooService", "Get", 5, "1", 1200))
+	b.Add(newLog("foo.FooService", "Put", 13, "2", 1300))
+	b.Add(newLog("bar.BarService", "Get", 13, "", 1400))
+	b.Add(newLog("unowned.Service", "Get", 13, "", 1500))
+	b.AddGroups([]*pb.ErrorGroup{
+		{Fingerprint: "aaaaaaaaaaaa", ServiceName: "foo.FooService", MethodName: "Put", ErrorCode: 13, Message: "disk full", FirstSeen: 1300, Count: 1},
+		{Fingerprint: "bbbbbbbbbbbb", ServiceName: "foo.FooService", MethodName: "Get", ErrorCode: 5, Message: "not found", FirstSeen: 500, Count: 2},
+	})
+	digests := b.Digests()
+	if len(digests) != 2 || digests[0].Recipient != "all@example.com" || len(digests[0].Services) != 2 || len(digests[1].Services) != 1 {
+		t.Fatalf("wrong digests: %v", digests)
+	}
+	foo := digests[1].Services[0]
+	if foo.Total != 3 || len(foo.Counts) != 2 || foo.Counts[0].Count != 2 || foo.Users[0].UserID != "1" || len(foo.NewGroups) != 1 {
+		t.Errorf("wrong summary: %v", foo)
+	}
+	text, err := digests[1].Render()
+	if err != nil {
+		t.Fatalf("failed to render: %s", err)
+	}
+	for _, s := range []string{"== foo.FooService: 3 errors", "2 Get NotFound", "1 Put Internal disk full (aaaaaaaa)", "2 1"} {
+		if !strings.Contains(text, s) {
+			t.Errorf("digest does not contain \"%s\":\n%s", s, text)
+		}
+	}
+
+	// sent
+	f := newFakeSMTP(t)
+	defer f.l.Close()
+	m := &Mailer{Relay: f.l.Addr().String(), From: "errorlogger@example.com"}
+	err = m.SendDigests(digests)
+	if err != nil {
+		t.Fatalf("failed to send: %s", err)
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if len(f.mails) != 2 {
+		t.Fatalf("expected 2 mails, got %d", len(f.mails))
+	}
+	mail := f.mails[1]
+	if mail.from != "errorlogger@example.com" || len(mail.to) != 1 || mail.to[0] != "foo@example.com" {
+		t.Errorf("wrong envelope: %v", mail)
+	}
+	if !strings.Contains(mail.data, "Subject: Errors from") || !strings.Contains(mail.data, "\r\n== foo.FooService: 3 errors\r\n") {
+		t.Errorf("wrong mail:\n%s", mail.data)
+	}
+}
@@ -0,0 +1,47 @@
+package digest
+
+import (
+	"fmt"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// sends emails through an SMTP relay
+type Mailer struct {
+	Relay string    // host:port
+	From  string    // the sender address
+	Auth  smtp.Auth // optional
+}
+
+// send a plain text email
+func (m *Mailer) Send(to []string, subject, body string) error {
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", m.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return smtp.SendMail(m.Relay, m.Auth, m.From, to, []byte(msg.String()))
+}
+
+// build the digests' emails and send them
+func (m *Mailer) SendDigests(digests []*Digest) error {
+	var errs []string
+	for _, d := range digests {
+		body, err := d.Render()
+		if err == nil {
+			err = m.Send([]string{d.Recipient}, d.Subject(), body)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", d.Recipient, err))
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("failed to send %d of %d digests: %s", len(errs), len(digests), strings.Join(errs, ", "))
+	}
+	return nil
+}
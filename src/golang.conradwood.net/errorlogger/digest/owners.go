@@ -0,0 +1,84 @@
+package digest
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.conradwood.net/errorlogger/ownership"
+	"golang.conradwood.net/go-easyops/utils"
+	"gopkg.in/yaml.v2"
+)
+
+/*
+the owners of services. for example:
+
+	owners:
+	  - services: ["foo.*", "bar.BarService"]
+	    emails: [team-foo@example.com]
+*/
+type Owner struct {
+	Services []string `yaml:"services"` // case-insensitive patterns of service names, see ownership.Match()
+	Emails   []string `yaml:"emails"`
+}
+
+type ownerFile struct {
+	Owners []*Owner `yaml:"owners"`
+}
+
+// read owners from a yaml file
+func LoadOwners(filename string) ([]*Owner, error) {
+	bs, err := utils.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := ParseOwners(bs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return owners, nil
+}
+
+// parse and check owners in yaml
+func ParseOwners(bs []byte) ([]*Owner, error) {
+	of := &ownerFile{}
+	err := yaml.UnmarshalStrict(bs, of)
+	if err != nil {
+		return nil, err
+	}
+	for i, o := range of.Owners {
+		if len(o.Services) == 0 || len(o.Emails) == 0 {
+			return nil, fmt.Errorf("owner #%d needs services and emails", i+1)
+		}
+		for _, s := range o.Services {
+			err = ownership.ValidPattern(s)
+			if err != nil {
+				return nil, fmt.Errorf("owner #%d: %s", i+1, err)
+			}
+		}
+	}
+	return of.Owners, nil
+}
+
+// true if the owner owns the service
+func (o *Owner) Owns(service string) bool {
+	return ownership.Match(o.Services, service)
+}
+
+// the email addresses of all owners of a service, sorted
+func Recipients(owners []*Owner, service string) []string {
+	m := make(map[string]bool)
+	for _, o := range owners {
+		if !o.Owns(service) {
+			continue
+		}
+		for _, e := range o.Emails {
+			m[e] = true
+		}
+	}
+	var res []string
+	for e := range m {
+		res = append(res, e)
+	}
+	sort.Strings(res)
+	return res
+}
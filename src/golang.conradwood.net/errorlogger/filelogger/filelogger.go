@@ -5,26 +5,105 @@ import (
 	"golang.conradwood.net/go-easyops/utils"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+)
+
+// options for Open(). nil means no rotation
+type Options struct {
+	MaxMB    int      // rotate the file once it reaches this size. 0 disables size-based rotation
+	Interval Interval // rotate the file at the start of each day or hour
+	// how many rotated files to keep. Without Interval the rotated files are filename.1, filename.2... and this defaults to 1.
+	// With Interval the rotated files are named after the period they cover (e.g. filename.20260131) and 0 keeps all of them
+	Generations int
+	MaxAge      time.Duration // delete rotated files older than this. 0 disables age-based deletion
+	Compress    bool          // gzip rotated files in the background
+	Sync        SyncPolicy    // when written data is flushed to disk
+}
+
+var (
+	open_lock    sync.Mutex
+	open_loggers = make(map[*FileLogger]bool) // for ReopenAll()
 )
 
 type FileLogger struct {
-	filename    string
-	maxmb       int
-	rotate_lock sync.Mutex
-	logfile     *os.File
+	filename      string
+	opts          Options
+	maxbytes      int64
+	size          int64
+	period        string // with Interval: the period the current file covers
+	rotate_lock   sync.Mutex
+	compress_lock sync.Mutex // held while rotated files are renamed, compressed or deleted
+	background    sync.WaitGroup
+	logfile       *os.File
+	dirty         bool          // written to since the last fsync
+	stop_sync     chan struct{} // stops periodic syncs, nil if there are none
+	now           func() time.Time
 }
 
-func Open(filename string) (*FileLogger, error) {
+func Open(filename string, opts *Options) (*FileLogger, error) {
 	var err error
-	f := &FileLogger{filename: filename, maxmb: 100}
+	if opts == nil {
+		opts = &Options{}
+	}
+	f := &FileLogger{
+		filename: filename,
+		opts:     *opts,
+		maxbytes: int64(opts.MaxMB) * 1024 * 1024,
+		now:      time.Now,
+	}
+	if f.opts.Interval == ROTATE_NONE && f.opts.Generations < 1 {
+		f.opts.Generations = 1
+	}
 	dir := filepath.Dir(f.filename)
 	err = os.MkdirAll(dir, 0777)
 	if err != nil {
 		return nil, err
 	}
+	err = f.open()
+	if err != nil {
+		return f, err
+	}
+	if f.opts.Interval != ROTATE_NONE {
+		// a non-empty file covers the period in which it was last written to
+		f.period = f.opts.Interval.period(f.now())
+		if f.size > 0 {
+			fi, err := os.Stat(f.filename)
+			if err == nil {
+				f.period = f.opts.Interval.period(fi.ModTime())
+			}
+		}
+	}
+	open_lock.Lock()
+	open_loggers[f] = true
+	open_lock.Unlock()
+	if f.opts.Sync.Mode == SYNC_PERIODIC {
+		f.stop_sync = make(chan struct{})
+		go f.syncPeriodically(f.stop_sync)
+	}
+	if f.opts.Compress || f.opts.MaxAge != 0 {
+		// finish what a previous run may have left
+		f.background.Add(1)
+		go f.cleanup()
+	}
+	return f, nil
+}
+
+// open the file and get its current size
+func (f *FileLogger) open() error {
+	var err error
 	f.logfile, err = utils.OpenWriteFile(f.filename)
-	return f, err
+	if err != nil {
+		return err
+	}
+	fi, err := f.logfile.Stat()
+	if err != nil {
+		return err
+	}
+	f.size = fi.Size()
+	return nil
 }
 
 func (f *FileLogger) WriteString(s string) error {
@@ -34,58 +113,227 @@ func (f *FileLogger) WriteString(s string) error {
 func (f *FileLogger) Write(b []byte) (int, error) {
 	f.rotate_lock.Lock()
 	defer f.rotate_lock.Unlock()
+	if f.opts.Interval != ROTATE_NONE && f.opts.Interval.period(f.now()) != f.period {
+		if f.size > 0 {
+			f.rotate()
+		} else {
+			f.period = f.opts.Interval.period(f.now())
+		}
+	} else if f.maxbytes > 0 && f.size > 0 && f.size+int64(len(b)) > f.maxbytes {
+		f.rotate()
+	}
+	if f.logfile == nil {
+		return 0, fmt.Errorf("logfile %s not open", f.filename)
+	}
 	n, err := f.logfile.Write(b)
+	f.size = f.size + int64(n)
+	f.dirty = true
 	if err != nil {
 		fmt.Printf("Failed to log \"%s\": %s\n", string(b), err)
+		return n, err
+	}
+	if f.opts.Sync.Mode == SYNC_ALWAYS {
+		err = f.sync()
 	}
 	return n, err
 }
-func (f *FileLogger) rotate() {
-	fi, err := os.Stat(f.filename)
-	if err != nil {
-		fmt.Printf("[rotate] Unable to stat %s: %s\n", f.filename, err)
-		return
+
+// close the file and open it again, e.g. after an external logrotate moved it. Writes wait until it is reopened
+func (f *FileLogger) Reopen() error {
+	f.rotate_lock.Lock()
+	defer f.rotate_lock.Unlock()
+	f.closeFile()
+	return f.open()
+}
+
+// reopen all FileLoggers which are open. returns the first error, but tries to reopen all of them
+func ReopenAll() error {
+	open_lock.Lock()
+	var loggers []*FileLogger
+	for f := range open_loggers {
+		loggers = append(loggers, f)
 	}
-	// get the size
-	size := fi.Size()
-	maxmb := (int64(f.maxmb) * 1024 * 1024)
-	if size < maxmb {
-		return
+	open_lock.Unlock()
+	var res error
+	for _, f := range loggers {
+		err := f.Reopen()
+		if err != nil {
+			fmt.Printf("Failed to reopen %s: %s\n", f.filename, err)
+			if res == nil {
+				res = err
+			}
+		}
 	}
-	fmt.Printf("[rotate] Filesize: %d (max: %d), wait for lock\n", size, maxmb)
+	return res
+}
+
+// close the file and wait for background compression to finish
+func (f *FileLogger) Close() error {
+	open_lock.Lock()
+	delete(open_loggers, f)
+	open_lock.Unlock()
 	f.rotate_lock.Lock()
 	defer f.rotate_lock.Unlock()
-	fmt.Printf("[rotate] Filesize: %d (max: %d), got lock\n", size, maxmb)
+	if f.stop_sync != nil {
+		close(f.stop_sync)
+		f.stop_sync = nil
+	}
+	f.background.Wait()
+	return f.closeFile()
+}
 
-	// check again once we got the lock
-	fi, err = os.Stat(f.filename)
-	if err != nil {
-		fmt.Printf("[rotate] Unable to stat %s: %s\n", f.filename, err)
-		return
+// sync (unless the policy leaves it to the operating system) and close the file. must be called with rotate_lock held
+func (f *FileLogger) closeFile() error {
+	if f.logfile == nil {
+		return nil
 	}
-	// get the size
-	size = fi.Size()
-	maxmb = (int64(f.maxmb) * 1024 * 1024)
-	if size < maxmb {
-		return
+	if f.opts.Sync.Mode != SYNC_NONE {
+		f.sync()
 	}
+	err := f.logfile.Close()
+	f.logfile = nil
+	f.dirty = false
+	return err
+}
+
+// the name of rotated generation n
+func (f *FileLogger) generationFilename(n int) string {
+	return fmt.Sprintf("%s.%d", f.filename, n)
+}
 
-	fmt.Printf("[rotate] rotating...\n")
-	newFilename := fmt.Sprintf("%s.1", f.filename)
-	os.Remove(newFilename)
-	err = os.Rename(f.filename, newFilename)
+// the name for the next rotated file of a period: filename.period, or filename.period.1 etc if rotated by size within the period
+func (f *FileLogger) periodFilename(period string) string {
+	base := fmt.Sprintf("%s.%s", f.filename, period)
+	res := base
+	for n := 1; exists(res) || exists(res+".gz"); n++ {
+		res = fmt.Sprintf("%s.%d", base, n)
+	}
+	return res
+}
+
+// rotate the current file away and open a new one. must be called with rotate_lock held
+func (f *FileLogger) rotate() {
+	fmt.Printf("[rotate] rotating %s (size: %d, max: %d)...\n", f.filename, f.size, f.maxbytes)
+	f.closeFile()
+	f.compress_lock.Lock()
+	var newFilename string
+	if f.opts.Interval == ROTATE_NONE {
+		// move filename.1 to filename.2 and so on
+		for _, suffix := range []string{"", ".gz"} {
+			os.Remove(f.generationFilename(f.opts.Generations) + suffix)
+			for i := f.opts.Generations - 1; i >= 1; i-- {
+				err := os.Rename(f.generationFilename(i)+suffix, f.generationFilename(i+1)+suffix)
+				if err != nil && !os.IsNotExist(err) {
+					fmt.Printf("[rotate] failed to rename %s: %s\n", f.generationFilename(i)+suffix, err)
+				}
+			}
+		}
+		newFilename = f.generationFilename(1)
+	} else {
+		newFilename = f.periodFilename(f.period)
+		f.period = f.opts.Interval.period(f.now())
+	}
+	err := os.Rename(f.filename, newFilename)
 	if err != nil {
 		fmt.Printf("[rotate] failed to rename %s to %s: %s\n", f.filename, newFilename, err)
-		return
-	}
-	if f.logfile != nil {
-		f.logfile.Close()
-		f.logfile = nil
 	}
-	f.logfile, err = utils.OpenWriteFile(f.filename)
+	f.compress_lock.Unlock()
+	err = f.open()
 	if err != nil {
 		fmt.Printf("[rotate] Failed to open logfile: %s\n", err)
+	}
+	if f.opts.Compress || f.opts.MaxAge != 0 || f.opts.Interval != ROTATE_NONE {
+		f.background.Add(1)
+		go f.cleanup()
+	}
+}
+
+// compress rotated files and delete those which are too old or too many
+func (f *FileLogger) cleanup() {
+	defer f.background.Done()
+	f.compress_lock.Lock()
+	defer f.compress_lock.Unlock()
+	rotated := f.rotatedFiles()
+	if f.opts.Compress {
+		for i, fname := range rotated {
+			if strings.HasSuffix(fname, ".gz") {
+				continue
+			}
+			err := compressFile(fname)
+			if err != nil {
+				fmt.Printf("[rotate] failed to compress %s: %s\n", fname, err)
+				continue
+			}
+			rotated[i] = fname + ".gz"
+		}
+	}
+
+	if f.opts.Interval == ROTATE_NONE && f.opts.MaxAge == 0 {
+		// number of generations is limited by rotate()
 		return
 	}
+	// newest first
+	type rotatedFile struct {
+		name  string
+		mtime time.Time
+	}
+	var files []*rotatedFile
+	for _, fname := range rotated {
+		fi, err := os.Stat(fname)
+		if err != nil {
+			continue
+		}
+		files = append(files, &rotatedFile{name: fname, mtime: fi.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].mtime.After(files[j].mtime)
+	})
+	now := f.now()
+	for i, rf := range files {
+		expired := f.opts.MaxAge != 0 && now.Sub(rf.mtime) > f.opts.MaxAge
+		if f.opts.Interval != ROTATE_NONE && f.opts.Generations > 0 && i >= f.opts.Generations {
+			expired = true
+		}
+		if !expired {
+			continue
+		}
+		fmt.Printf("[rotate] removing %s\n", rf.name)
+		err := os.Remove(rf.name)
+		if err != nil {
+			fmt.Printf("[rotate] failed to remove %s: %s\n", rf.name, err)
+		}
+	}
+}
+
+// all rotated files of this logger (filename.1, filename.20260131.gz etc). Removes leftovers of interrupted compressions
+func (f *FileLogger) rotatedFiles() []string {
+	names, err := filepath.Glob(escapeGlob(f.filename) + ".*")
+	if err != nil {
+		fmt.Printf("[rotate] failed to list rotated files of %s: %s\n", f.filename, err)
+		return nil
+	}
+	var res []string
+	for _, name := range names {
+		suffix := strings.TrimPrefix(name, f.filename+".")
+		if suffix == "" || suffix[0] < '0' || suffix[0] > '9' {
+			// not a rotated file, e.g. an index
+			continue
+		}
+		if strings.HasSuffix(name, compress_tmp_suffix) {
+			os.Remove(name)
+			continue
+		}
+		res = append(res, name)
+	}
+	return res
+}
+
+func escapeGlob(s string) string {
+	r := strings.NewReplacer("*", "\\*", "?", "\\?", "[", "\\[", "\\", "\\\\")
+	return r.Replace(s)
+}
 
+func exists(filename string) bool {
+	_, err := os.Stat(filename)
+	return err == nil
 }
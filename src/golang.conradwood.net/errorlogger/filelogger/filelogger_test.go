@@ -0,0 +1,325 @@
+package filelogger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSizeRotation(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "all.log")
+	fl, err := Open(fname, &Options{MaxMB: 1, Generations: 3})
+	if err != nil {
+		t.Fatalf("failed to open: %s", err)
+	}
+	fl.maxbytes = 100 // rotate quickly
+	defer fl.Close()
+	line := "0123456789012345678901234567890123456789\n"
+	for i := 0; i < 20; i++ {
+		err = fl.WriteString(line)
+		if err != nil {
+			t.Fatalf("failed to write: %s", err)
+		}
+	}
+	// 20 lines, 2 per file
+	for _, name := range []string{fname, fname + ".1", fname + ".2", fname + ".3"} {
+		st, err := os.Stat(name)
+		if err != nil {
+			t.Errorf("expected %s to exist: %s", name, err)
+			continue
+		}
+		if st.Size() != int64(2*len(line)) {
+			t.Errorf("%s has %d bytes, expected %d", name, st.Size(), 2*len(line))
+		}
+	}
+	_, err = os.Stat(fmt.Sprintf("%s.4", fname))
+	if !os.IsNotExist(err) {
+		t.Errorf("expected only 3 generations to be kept (%v)", err)
+	}
+
+	// re-opening continues with the size of the existing file
+	fl, err = Open(fname, &Options{MaxMB: 1, Generations: 3})
+	if err != nil {
+		t.Fatalf("failed to re-open: %s", err)
+	}
+	defer fl.Close()
+	fl.maxbytes = 100
+	fl.WriteString(line)
+	st, _ := os.Stat(fname)
+	if st.Size() != int64(len(line)) {
+		t.Errorf("expected rotation after re-opening, file has %d bytes", st.Size())
+	}
+}
+
+func TestNoRotation(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "proto.log")
+	fl, err := Open(fname, nil)
+	if err != nil {
+		t.Fatalf("failed to open: %s", err)
+	}
+	defer fl.Close()
+	for i := 0; i < 100; i++ {
+		fl.WriteString("0123456789")
+	}
+	st, _ := os.Stat(fname)
+	if st.Size() != 1000 {
+		t.Errorf("expected 1000 bytes, got %d", st.Size())
+	}
+}
+
+func readGzip(t *testing.T, filename string) string {
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("failed to open %s: %s", filename, err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", filename, err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to read %s: %s", filename, err)
+	}
+	return string(b)
+}
+
+func TestTimeRotation(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "all.log")
+	now := time.Date(2026, 1, 30, 23, 0, 0, 0, time.UTC)
+	fl, err := Open(fname, &Options{Interval: ROTATE_DAILY, Compress: true, MaxAge: 48 * time.Hour})
+	if err != nil {
+		t.Fatalf("failed to open: %s", err)
+	}
+	// the cleanup started by Open() uses the clock, too
+	fl.background.Wait()
+	fl.now = func() time.Time { return now }
+	fl.period = ROTATE_DAILY.period(now)
+	fl.WriteString("day 1\n")
+
+	now = now.Add(2 * time.Hour)
+	fl.WriteString("day 2\n")
+	fl.background.Wait()
+	if got := readGzip(t, fname+".20260130.gz"); got != "day 1\n" {
+		t.Errorf("rotated file contains \"%s\"", got)
+	}
+	if exists(fname + ".20260130") {
+		t.Errorf("uncompressed file was not removed")
+	}
+	b, _ := os.ReadFile(fname)
+	if string(b) != "day 2\n" {
+		t.Errorf("current file contains \"%s\"", string(b))
+	}
+
+	// rotated files older than MaxAge are removed
+	old := now.Add(-72 * time.Hour)
+	os.Chtimes(fname+".20260130.gz", old, old)
+	now = now.Add(24 * time.Hour)
+	fl.WriteString("day 3\n")
+	fl.Close()
+	if exists(fname + ".20260130.gz") {
+		t.Errorf("expired file was not removed")
+	}
+	if got := readGzip(t, fname+".20260131.gz"); got != "day 2\n" {
+		t.Errorf("rotated file contains \"%s\"", got)
+	}
+}
+
+func TestTimeRotationGenerations(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "all.log")
+	now := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	fl, err := Open(fname, &Options{MaxMB: 1, Interval: ROTATE_HOURLY, Generations: 2})
+	if err != nil {
+		t.Fatalf("failed to open: %s", err)
+	}
+	fl.now = func() time.Time { return now }
+	fl.period = ROTATE_HOURLY.period(now)
+	fl.maxbytes = 10
+	// two files within the same hour, rotated by size
+	fl.WriteString("0123456789")
+	fl.WriteString("0123456789")
+	fl.background.Wait()
+	if !exists(fname + ".20260130-10") {
+		t.Errorf("expected %s.20260130-10 to exist", fname)
+	}
+	for h := 1; h < 4; h++ {
+		now = now.Add(time.Hour)
+		// make sure the files can be told apart by their modification time
+		st, _ := os.Stat(fname)
+		os.Chtimes(fname, st.ModTime().Add(time.Duration(h)*time.Second), st.ModTime().Add(time.Duration(h)*time.Second))
+		fl.WriteString(fmt.Sprintf("hour %d", h))
+	}
+	fl.Close()
+	names := fl.rotatedFiles()
+	if len(names) != 2 {
+		t.Fatalf("expected 2 rotated files, got %v", names)
+	}
+	for _, name := range []string{fname + ".20260130-12", fname + ".20260130-11"} {
+		if !exists(name) {
+			t.Errorf("expected %s to be kept (have %v)", name, names)
+		}
+	}
+}
+
+func TestReopen(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "all.log")
+	fl, err := Open(fname, nil)
+	if err != nil {
+		t.Fatalf("failed to open: %s", err)
+	}
+	defer fl.Close()
+	fl.WriteString("before\n")
+	// what an external logrotate does
+	err = os.Rename(fname, fname+".1")
+	if err != nil {
+		t.Fatalf("failed to rename: %s", err)
+	}
+	fl.WriteString("moved\n")
+	err = ReopenAll()
+	if err != nil {
+		t.Fatalf("failed to reopen: %s", err)
+	}
+	fl.WriteString("after\n")
+	b, _ := os.ReadFile(fname + ".1")
+	if string(b) != "before\nmoved\n" {
+		t.Errorf("moved file contains \"%s\"", string(b))
+	}
+	b, _ = os.ReadFile(fname)
+	if string(b) != "after\n" {
+		t.Errorf("reopened file contains \"%s\"", string(b))
+	}
+}
+
+func TestSyncPolicy(t *testing.T) {
+	for s, expected := range map[string]SyncPolicy{
+		"none":   {Mode: SYNC_NONE},
+		"always": {Mode: SYNC_ALWAYS},
+		"250ms":  {Mode: SYNC_PERIODIC, Interval: 250 * time.Millisecond},
+	} {
+		got, err := ParseSyncPolicy(s)
+		if err != nil {
+			t.Errorf("failed to parse \"%s\": %s", s, err)
+		}
+		if got != expected {
+			t.Errorf("\"%s\" parsed as %v, expected %v", s, got, expected)
+		}
+	}
+	for _, s := range []string{"sometimes", "-1s", "0"} {
+		_, err := ParseSyncPolicy(s)
+		if err == nil {
+			t.Errorf("\"%s\" parsed without error", s)
+		}
+	}
+
+	fname := filepath.Join(t.TempDir(), "proto.log")
+	fl, err := Open(fname, &Options{Sync: SyncPolicy{Mode: SYNC_ALWAYS}})
+	if err != nil {
+		t.Fatalf("failed to open: %s", err)
+	}
+	fl.WriteString("synced")
+	if fl.dirty {
+		t.Errorf("file not synced after write")
+	}
+	fl.Close()
+
+	fl, err = Open(fname, &Options{Sync: SyncPolicy{Mode: SYNC_PERIODIC, Interval: 10 * time.Millisecond}})
+	if err != nil {
+		t.Fatalf("failed to open: %s", err)
+	}
+	defer fl.Close()
+	fl.WriteString("synced later")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		fl.rotate_lock.Lock()
+		dirty := fl.dirty
+		fl.rotate_lock.Unlock()
+		if !dirty {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("file not synced periodically")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// run with -race
+func TestPool(t *testing.T) {
+	dir := t.TempDir()
+	p := &Pool{MaxOpen: 3, IdleTimeout: 20 * time.Millisecond}
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				fname := filepath.Join(dir, fmt.Sprintf("user%d.log", (w+i)%10))
+				_, err := p.Write(fname, []byte(fmt.Sprintf("%d %d\n", w, i)))
+				if err != nil {
+					t.Errorf("failed to write: %s", err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	if p.Len() > 3 {
+		t.Errorf("%d loggers open, expected at most 3", p.Len())
+	}
+	lines := 0
+	for u := 0; u < 10; u++ {
+		b, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("user%d.log", u)))
+		if err != nil {
+			t.Fatalf("failed to read: %s", err)
+		}
+		lines = lines + strings.Count(string(b), "\n")
+	}
+	if lines != 800 {
+		t.Errorf("expected 800 lines, got %d", lines)
+	}
+
+	// idle loggers are closed
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d idle loggers not closed", p.Len())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	p.Close()
+}
+
+func TestPoolCloseInUse(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "user1.log")
+	p := &Pool{}
+	pl, err := p.acquire(fname)
+	if err != nil {
+		t.Fatalf("failed to open: %s", err)
+	}
+	// closed while a write is in progress
+	p.Close()
+	_, err = pl.fl.Write([]byte("in progress\n"))
+	if err != nil {
+		t.Errorf("logger closed while in use: %s", err)
+	}
+	p.release(pl)
+	if pl.fl.logfile != nil {
+		t.Errorf("logger not closed by the last write")
+	}
+	// opened again
+	_, err = p.Write(fname, []byte("after close\n"))
+	if err != nil {
+		t.Errorf("failed to write after close: %s", err)
+	}
+	p.Close()
+	b, _ := os.ReadFile(fname)
+	if string(b) != "in progress\nafter close\n" {
+		t.Errorf("file contains \"%s\"", string(b))
+	}
+}
@@ -0,0 +1,87 @@
+package filelogger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const (
+	compress_tmp_suffix = ".gz.tmp"
+)
+
+// time-based rotation
+type Interval int
+
+const (
+	ROTATE_NONE Interval = iota
+	ROTATE_DAILY
+	ROTATE_HOURLY
+)
+
+// parse "none", "daily" or "hourly"
+func ParseInterval(s string) (Interval, error) {
+	switch s {
+	case "", "none":
+		return ROTATE_NONE, nil
+	case "daily":
+		return ROTATE_DAILY, nil
+	case "hourly":
+		return ROTATE_HOURLY, nil
+	}
+	return ROTATE_NONE, fmt.Errorf("invalid rotation interval \"%s\" (valid: none, daily, hourly)", s)
+}
+
+// the name of the period t is in, used as suffix for rotated files
+func (i Interval) period(t time.Time) string {
+	switch i {
+	case ROTATE_DAILY:
+		return t.Format("20060102")
+	case ROTATE_HOURLY:
+		return t.Format("20060102-15")
+	}
+	return ""
+}
+
+// gzip filename to filename.gz and remove filename. The compressed file keeps the modification time
+func compressFile(filename string) error {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmpname := filename + compress_tmp_suffix
+	out, err := os.Create(tmpname)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	cerr := out.Close()
+	if err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmpname)
+		return err
+	}
+	err = os.Rename(tmpname, filename+".gz")
+	if err != nil {
+		os.Remove(tmpname)
+		return err
+	}
+	os.Chtimes(filename+".gz", fi.ModTime(), fi.ModTime())
+	return os.Remove(filename)
+}
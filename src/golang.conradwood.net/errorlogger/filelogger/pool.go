@@ -0,0 +1,181 @@
+package filelogger
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// a bounded set of open FileLoggers, e.g. one per user. Loggers are opened on demand, the least recently used ones
+// are closed if more than MaxOpen are open, and idle ones are closed after IdleTimeout
+type Pool struct {
+	Options     *Options      // used to open all loggers of the pool
+	MaxOpen     int           // defaults to 100. loggers which are being written to are not closed, so there may be more temporarily
+	IdleTimeout time.Duration // close loggers which were not written to for this long. 0 keeps them open until MaxOpen is reached
+	lock        sync.Mutex
+	start       sync.Once
+	loggers     map[string]*list.Element
+	lru         *list.List // most recently used first
+	stop        chan struct{}
+}
+
+type pooledLogger struct {
+	filename  string
+	fl        *FileLogger
+	refs      int // writes in progress
+	last_used time.Time
+	removed   bool // removed from the pool while in use, closed by the last release()
+}
+
+func (p *Pool) init() {
+	p.start.Do(func() {
+		if p.MaxOpen <= 0 {
+			p.MaxOpen = 100
+		}
+		p.loggers = make(map[string]*list.Element)
+		p.lru = list.New()
+		p.stop = make(chan struct{})
+		if p.IdleTimeout > 0 {
+			go p.closeIdle()
+		}
+	})
+}
+
+// write to the logger for filename, opening it if necessary
+func (p *Pool) Write(filename string, b []byte) (int, error) {
+	pl, err := p.acquire(filename)
+	if err != nil {
+		return 0, err
+	}
+	n, err := pl.fl.Write(b)
+	p.release(pl)
+	return n, err
+}
+
+// the number of open loggers
+func (p *Pool) Len() int {
+	p.init()
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.lru.Len()
+}
+
+// get the logger for filename and mark it as in use
+func (p *Pool) acquire(filename string) (*pooledLogger, error) {
+	p.init()
+	p.lock.Lock()
+	el := p.loggers[filename]
+	if el != nil {
+		pl := el.Value.(*pooledLogger)
+		pl.refs++
+		p.lru.MoveToFront(el)
+		p.lock.Unlock()
+		return pl, nil
+	}
+	p.lock.Unlock()
+
+	// opening may take a while, writes to other loggers of the pool go ahead meanwhile
+	fl, err := Open(filename, p.Options)
+	if err != nil {
+		return nil, err
+	}
+	p.lock.Lock()
+	el = p.loggers[filename]
+	if el != nil {
+		// opened by a concurrent write
+		pl := el.Value.(*pooledLogger)
+		pl.refs++
+		p.lru.MoveToFront(el)
+		p.lock.Unlock()
+		closeLoggers([]*pooledLogger{{filename: filename, fl: fl}})
+		return pl, nil
+	}
+	pl := &pooledLogger{filename: filename, fl: fl, refs: 1}
+	p.loggers[filename] = p.lru.PushFront(pl)
+	var evicted []*pooledLogger
+	for el := p.lru.Back(); el != nil && p.lru.Len() > p.MaxOpen; {
+		prev := el.Prev()
+		if c := el.Value.(*pooledLogger); c.refs == 0 {
+			p.remove(el)
+			evicted = append(evicted, c)
+		}
+		el = prev
+	}
+	p.lock.Unlock()
+	closeLoggers(evicted)
+	return pl, nil
+}
+
+// mark a logger acquire() returned as no longer in use
+func (p *Pool) release(pl *pooledLogger) {
+	p.lock.Lock()
+	pl.refs--
+	pl.last_used = time.Now()
+	closing := pl.removed && pl.refs == 0
+	p.lock.Unlock()
+	if closing {
+		closeLoggers([]*pooledLogger{pl})
+	}
+}
+
+// must be called with lock held
+func (p *Pool) remove(el *list.Element) {
+	p.lru.Remove(el)
+	delete(p.loggers, el.Value.(*pooledLogger).filename)
+}
+
+// close loggers which were idle for longer than IdleTimeout
+func (p *Pool) closeIdle() {
+	t := time.NewTicker(p.IdleTimeout / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-t.C:
+		}
+		var idle []*pooledLogger
+		p.lock.Lock()
+		for el := p.lru.Back(); el != nil; {
+			prev := el.Prev()
+			if c := el.Value.(*pooledLogger); c.refs == 0 && time.Since(c.last_used) > p.IdleTimeout {
+				p.remove(el)
+				idle = append(idle, c)
+			}
+			el = prev
+		}
+		p.lock.Unlock()
+		closeLoggers(idle)
+	}
+}
+
+// close all loggers of the pool. Loggers which are being written to are closed once the write completes.
+// Loggers are opened again if the pool is written to afterwards
+func (p *Pool) Close() {
+	p.init()
+	var all []*pooledLogger
+	p.lock.Lock()
+	for el := p.lru.Front(); el != nil; el = el.Next() {
+		pl := el.Value.(*pooledLogger)
+		if pl.refs > 0 {
+			pl.removed = true
+			continue
+		}
+		all = append(all, pl)
+	}
+	p.loggers = make(map[string]*list.Element)
+	p.lru.Init()
+	p.lock.Unlock()
+	closeLoggers(all)
+}
+
+// close loggers which were removed from the pool. called without the pool lock held, Close() may wait for compression
+func closeLoggers(pls []*pooledLogger) {
+	for _, pl := range pls {
+		err := pl.fl.Close()
+		if err != nil {
+			fmt.Printf("Failed to close %s: %s\n", pl.filename, err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package filelogger
+
+import (
+	"fmt"
+	"time"
+)
+
+// when data written to a FileLogger is flushed to disk
+type SyncMode int
+
+const (
+	SYNC_NONE     SyncMode = iota // leave it to the operating system
+	SYNC_ALWAYS                   // fsync after every write
+	SYNC_PERIODIC                 // fsync every Interval if something was written
+)
+
+type SyncPolicy struct {
+	Mode     SyncMode
+	Interval time.Duration // for SYNC_PERIODIC
+}
+
+// parse "none", "always" or an interval for periodic syncs, e.g. "500ms"
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	switch s {
+	case "", "none":
+		return SyncPolicy{Mode: SYNC_NONE}, nil
+	case "always":
+		return SyncPolicy{Mode: SYNC_ALWAYS}, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return SyncPolicy{}, fmt.Errorf("invalid sync policy \"%s\" (valid: none, always or an interval such as 500ms)", s)
+	}
+	return SyncPolicy{Mode: SYNC_PERIODIC, Interval: d}, nil
+}
+
+func (s SyncPolicy) String() string {
+	switch s.Mode {
+	case SYNC_ALWAYS:
+		return "always"
+	case SYNC_PERIODIC:
+		return s.Interval.String()
+	}
+	return "none"
+}
+
+// fsync the file every Interval until stop is closed
+func (f *FileLogger) syncPeriodically(stop chan struct{}) {
+	t := time.NewTicker(f.opts.Sync.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		f.rotate_lock.Lock()
+		f.sync()
+		f.rotate_lock.Unlock()
+	}
+}
+
+// fsync the file if something was written since the last sync. must be called with rotate_lock held
+func (f *FileLogger) sync() error {
+	if !f.dirty || f.logfile == nil {
+		return nil
+	}
+	err := f.logfile.Sync()
+	if err != nil {
+		fmt.Printf("Failed to sync %s: %s\n", f.filename, err)
+		return err
+	}
+	f.dirty = false
+	return nil
+}
@@ -0,0 +1,98 @@
+package main
+
+/*
+check a proto.log for damaged entries and optionally write a repaired copy which contains only the valid entries
+*/
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/errorlogger/streamblock"
+	"golang.conradwood.net/go-easyops/utils"
+)
+
+var (
+	filename  = flag.String("file", "/var/log/errorlogger/proto.log", "`filename` of the proto.log (or one of its segments, e.g. proto.log.000001) to check")
+	repair    = flag.String("repair", "", "if set, write the valid entries to this `filename`")
+	checksums = flag.Bool("checksums", true, "write a checksum with each entry of the repaired file")
+	verbose   = flag.Bool("verbose", false, "print every entry")
+)
+
+func main() {
+	flag.Parse()
+	f, err := os.Open(*filename)
+	utils.Bail("failed to open file", err)
+	defer f.Close()
+
+	var rf *os.File
+	var out io.Writer
+	if *repair != "" {
+		a, _ := filepath.Abs(*filename)
+		b, _ := filepath.Abs(*repair)
+		if a == b {
+			fmt.Printf("Cannot repair in place, please specify a different file\n")
+			os.Exit(10)
+		}
+		rf, err = os.Create(*repair)
+		utils.Bail("failed to create repaired file", err)
+		framing := streamblock.FRAMING_PLAIN
+		if *checksums {
+			framing = streamblock.FRAMING_CHECKSUM
+		}
+		out = streamblock.NewBlockWriterWithFraming(rf, framing)
+	}
+
+	valid := 0
+	damaged := 0
+	undecodable := 0
+	s := streamblock.NewScanner(f)
+	for {
+		sb, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		utils.Bail("failed to read file", err)
+		if sb.Err != nil {
+			fmt.Printf("%12d: %s (%d bytes)\n", sb.Offset, sb.Err, sb.Length)
+			damaged++
+			continue
+		}
+		pl := &pb.ProtoLog{}
+		err = utils.UnmarshalBytes(sb.Data, pl)
+		if err != nil {
+			fmt.Printf("%12d: undecodable entry (%d bytes): %s\n", sb.Offset, sb.Length, err)
+			undecodable++
+			continue
+		}
+		if *verbose {
+			e := pl.Err
+			if e == nil {
+				e = &pb.ErrorLogRequest{}
+			}
+			fmt.Printf("%12d: %s %s.%s %d\n", sb.Offset, utils.TimestampString(pl.Received), e.ServiceName, e.MethodName, e.ErrorCode)
+		}
+		valid++
+		if out != nil {
+			_, err = out.Write(sb.Data)
+			utils.Bail("failed to write repaired file", err)
+		}
+	}
+
+	fmt.Printf("%d valid entries, %d damaged blocks, %d undecodable entries\n", valid, damaged, undecodable)
+	if rf != nil {
+		err = rf.Sync()
+		utils.Bail("failed to sync repaired file", err)
+		err = rf.Close()
+		utils.Bail("failed to close repaired file", err)
+		fmt.Printf("Wrote %d entries to %s. To use it, stop the server, replace %s with it and remove %s\n", valid, *repair, *filename, streamblock.IndexFilename(*filename))
+	}
+	if damaged != 0 || undecodable != 0 {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
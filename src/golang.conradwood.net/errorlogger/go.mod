@@ -8,6 +8,7 @@ require (
 	golang.conradwood.net/apis/errorlogger v1.1.4424
 	golang.conradwood.net/go-easyops v0.1.39553
 	google.golang.org/grpc v1.78.0
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
@@ -46,7 +47,6 @@ require (
 	golang.yacloud.eu/unixipc v0.1.31725 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20251029180050-ab9386a59fda // indirect
 	google.golang.org/protobuf v1.36.11 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
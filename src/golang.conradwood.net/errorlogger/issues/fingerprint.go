@@ -0,0 +1,38 @@
+package issues
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+)
+
+var (
+	uuid_re   = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	hexid_re  = regexp.MustCompile(`(?i)\b(0x)?[0-9a-f]{8,}\b`)
+	number_re = regexp.MustCompile(`[0-9]+`)
+)
+
+// the LogMessage with UUIDs, hex IDs and numbers replaced by placeholders, so that messages about the same problem are equal
+func Normalise(msg string) string {
+	msg = uuid_re.ReplaceAllString(msg, "<uuid>")
+	msg = hexid_re.ReplaceAllStringFunc(msg, func(s string) string {
+		t := strings.TrimPrefix(strings.ToLower(s), "0x")
+		if !strings.ContainsAny(t, "0123456789") || !strings.ContainsAny(t, "abcdef") {
+			// a word such as "deadbeef", or a number
+			return s
+		}
+		return "<id>"
+	})
+	return number_re.ReplaceAllString(msg, "<n>")
+}
+
+// identifies the problem an error is about: its service, method, code and normalised LogMessage
+func Fingerprint(req *pb.ErrorLogRequest) string {
+	s := fmt.Sprintf("%s\x00%s\x00%d\x00%s", req.ServiceName, req.MethodName, req.ErrorCode, Normalise(req.LogMessage))
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:16])
+}
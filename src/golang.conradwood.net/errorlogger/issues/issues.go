@@ -0,0 +1,280 @@
+/*
+groups errors by fingerprint (see Fingerprint()) and keeps aggregates per group
+*/
+package issues
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/go-easyops/utils"
+)
+
+const (
+	max_users_per_group = 1000
+)
+
+var (
+	ErrNotFound  = errors.New("no such group")
+	ErrAmbiguous = errors.New("fingerprint prefix matches more than one group")
+)
+
+type Tracker struct {
+	MaxGroups int // the least recently seen groups are forgotten if there are more. defaults to 10000
+	lock      sync.Mutex
+	groups    map[string]*group
+	lru       *list.List // most recently seen first
+	now       func() time.Time
+}
+
+// what Add() found out about an error
+type Added struct {
+	Fingerprint string
+	New         bool // the first error of its group
+	Muted       bool // its group is muted
+}
+
+type group struct {
+	eg    *pb.ErrorGroup
+	users map[string]bool
+	el    *list.Element // in Tracker.lru
+}
+
+// add an error to its group
+func (t *Tracker) Add(pl *pb.ProtoLog) Added {
+	if pl.Err == nil {
+		return Added{}
+	}
+	req := pl.Err
+	fp := Fingerprint(req)
+	ts := pl.Received
+	if ts == 0 {
+		ts = req.Timestamp
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.groups == nil {
+		t.groups = make(map[string]*group)
+		t.lru = list.New()
+	}
+	res := Added{Fingerprint: fp}
+	g := t.groups[fp]
+	if g == nil {
+		res.New = true
+		g = &group{
+			eg: &pb.ErrorGroup{
+				Fingerprint: fp,
+				ServiceName: req.ServiceName,
+				MethodName:  req.MethodName,
+				ErrorCode:   req.ErrorCode,
+				Message:     Normalise(req.LogMessage),
+				FirstSeen:   ts,
+			},
+			users: make(map[string]bool),
+		}
+		t.groups[fp] = g
+		g.el = t.lru.PushFront(g)
+	}
+	eg := g.eg
+	eg.Count++
+	if ts > eg.LastSeen {
+		eg.LastSeen = ts
+		t.lru.MoveToFront(g.el)
+	}
+	eg.Sample = pl
+	if req.UserID != "" && !g.users[req.UserID] && len(eg.UserIDs) < max_users_per_group {
+		g.users[req.UserID] = true
+		eg.UserIDs = append(eg.UserIDs, req.UserID)
+	}
+	switch eg.State {
+	case pb.GroupState_RESOLVED:
+		eg.State = pb.GroupState_REGRESSED
+		eg.StateChanged = ts
+	case pb.GroupState_MUTED:
+		expireMute(eg, ts)
+	}
+	res.Muted = eg.State == pb.GroupState_MUTED
+	t.limit()
+	return res
+}
+
+// change the state of a group. the fingerprint may be a unique prefix
+func (t *Tracker) SetState(req *pb.SetGroupStateRequest) (*pb.ErrorGroup, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	var eg *pb.ErrorGroup
+	for fp, g := range t.groups {
+		if req.Fingerprint == "" || !strings.HasPrefix(fp, req.Fingerprint) {
+			continue
+		}
+		if eg != nil {
+			return nil, ErrAmbiguous
+		}
+		eg = g.eg
+	}
+	if eg == nil {
+		return nil, ErrNotFound
+	}
+	eg.State = req.State
+	eg.StateChanged = uint32(t.timeNow().Unix())
+	eg.MutedUntil = 0
+	eg.MutedUntilCount = 0
+	if req.State == pb.GroupState_MUTED {
+		eg.MutedUntil = req.MuteUntil
+		if req.MuteOccurrences != 0 {
+			eg.MutedUntilCount = eg.Count + uint64(req.MuteOccurrences)
+		}
+	}
+	return copyGroup(eg), nil
+}
+
+// unmute a group if its mute expired at ts or with its current count
+func expireMute(eg *pb.ErrorGroup, ts uint32) {
+	if eg.State != pb.GroupState_MUTED {
+		return
+	}
+	if (eg.MutedUntil != 0 && ts >= eg.MutedUntil) || (eg.MutedUntilCount != 0 && eg.Count > eg.MutedUntilCount) {
+		eg.State = pb.GroupState_OPEN
+		eg.StateChanged = ts
+		eg.MutedUntil = 0
+		eg.MutedUntilCount = 0
+	}
+}
+
+func (t *Tracker) timeNow() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// forget the least recently seen group if there are too many. must be called with lock held
+func (t *Tracker) limit() {
+	max := t.MaxGroups
+	if max <= 0 {
+		max = 10000
+	}
+	for len(t.groups) > max {
+		oldest := t.lru.Remove(t.lru.Back()).(*group)
+		delete(t.groups, oldest.eg.Fingerprint)
+	}
+}
+
+// the groups matching the request, in the requested order. at most limit groups are returned, 0 returns all
+func (t *Tracker) List(req *pb.ListErrorGroupsRequest, limit int) []*pb.ErrorGroup {
+	var services []string
+	for _, s := range req.Services {
+		services = append(services, strings.ToLower(s))
+	}
+	states := make(map[pb.GroupState]bool)
+	for _, s := range req.States {
+		states[s] = true
+	}
+	now := uint32(t.timeNow().Unix())
+	var res []*pb.ErrorGroup
+	t.lock.Lock()
+	for _, g := range t.groups {
+		expireMute(g.eg, now)
+		if len(services) > 0 && !containsAny(strings.ToLower(g.eg.ServiceName), services) {
+			continue
+		}
+		if len(states) > 0 && !states[g.eg.State] {
+			continue
+		}
+		res = append(res, copyGroup(g.eg))
+	}
+	t.lock.Unlock()
+	sortGroups(res, req.Order)
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res
+}
+
+func sortGroups(groups []*pb.ErrorGroup, order pb.GroupOrder) {
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if order == pb.GroupOrder_BY_COUNT && a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.LastSeen != b.LastSeen {
+			return a.LastSeen > b.LastSeen
+		}
+		return a.Fingerprint < b.Fingerprint
+	})
+}
+
+// a copy which does not change when more errors are added to the group
+func copyGroup(eg *pb.ErrorGroup) *pb.ErrorGroup {
+	res := *eg
+	res.UserIDs = append([]string{}, eg.UserIDs...)
+	return &res
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// write all groups to a file
+func (t *Tracker) Save(filename string) error {
+	egl := &pb.ErrorGroupList{}
+	t.lock.Lock()
+	for _, g := range t.groups {
+		egl.Groups = append(egl.Groups, copyGroup(g.eg))
+	}
+	t.lock.Unlock()
+	bs, err := utils.MarshalBytes(egl)
+	if err != nil {
+		return err
+	}
+	tmpname := filename + ".tmp"
+	err = utils.WriteFile(tmpname, bs)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpname, filename)
+}
+
+// read groups saved with Save(). a missing file is not an error
+func (t *Tracker) Load(filename string) error {
+	if !utils.FileExists(filename) {
+		return nil
+	}
+	bs, err := utils.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	egl := &pb.ErrorGroupList{}
+	err = utils.UnmarshalBytes(bs, egl)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %s", filename, err)
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.groups = make(map[string]*group)
+	t.lru = list.New()
+	sort.Slice(egl.Groups, func(i, j int) bool {
+		return egl.Groups[i].LastSeen < egl.Groups[j].LastSeen
+	})
+	for _, eg := range egl.Groups {
+		g := &group{eg: eg, users: make(map[string]bool)}
+		for _, u := range eg.UserIDs {
+			g.users[u] = true
+		}
+		t.groups[eg.Fingerprint] = g
+		g.el = t.lru.PushFront(g)
+	}
+	return nil
+}
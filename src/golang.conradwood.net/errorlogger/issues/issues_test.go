@@ -0,0 +1,191 @@
+package issues
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+)
+
+func TestNormalise(t *testing.T) {
+	for msg, expected := range map[string]string{
+		"user 123 not found":                                      "user <n> not found",
+		"order 12345678 failed":                                   "order <n> failed",
+		"object 3f2504e0-4f89-11d3-9a0c-0305e82c3301 missing":     "object <uuid> missing",
+		"request deadbeef1234 timed out after 30s":                "request <id> timed out after <n>s",
+		"pointer 0x7ffe12ab":                                      "pointer <id>",
+		"deadbeef is a word":                                      "deadbeef is a word",
+		"failed to frobnicate":                                    "failed to frobnicate",
+		"user 7 (a@example.com) on 10.0.0.1:4100, 2 retries left": "user <n> (a@example.com) on <n>.<n>.<n>.<n>:<n>, <n> retries left",
+	} {
+		got := Normalise(msg)
+		if got != expected {
+			t.Errorf("normalised \"%s\" to \"%s\", expected \"%s\"", msg, got, expected)
+		}
+	}
+}
+
+func newLog(service, method string, code uint32, msg, user string, ts uint32) *pb.ProtoLog {
+	return &pb.ProtoLog{
+		Err:      &pb.ErrorLogRequest{ServiceName: service, MethodName: method, ErrorCode: code, LogMessage: msg, UserID: user},
+		Received: ts,
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tr := &Tracker{}
+	if !tr.Add(newLog("foo.Foo", "Get", 5, "user 1 not found", "1", 100)).New {
+		t.Errorf("first error of a group not reported as new")
+	}
+	if tr.Add(newLog("foo.Foo", "Get", 5, "user 2 not found", "2", 110)).New {
+		t.Errorf("second error of a group reported as new")
+	}
+	tr.Add(newLog("foo.Foo", "Get", 5, "user 1 not found", "1", 120))
+	tr.Add(newLog("foo.Foo", "Get", 13, "user 1 not found", "1", 105)) // different code
+	tr.Add(newLog("bar.Bar", "Put", 13, "disk full", "", 130))
+
+	groups := tr.List(&pb.ListErrorGroupsRequest{Order: pb.GroupOrder_BY_COUNT}, 0)
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got %d", len(groups))
+	}
+	g := groups[0]
+	if g.Count != 3 || g.FirstSeen != 100 || g.LastSeen != 120 || g.Message != "user <n> not found" {
+		t.Errorf("wrong aggregates: %v", g)
+	}
+	if len(g.UserIDs) != 2 || g.UserIDs[0] != "1" || g.UserIDs[1] != "2" {
+		t.Errorf("wrong users: %v", g.UserIDs)
+	}
+	if g.Sample == nil || g.Sample.Received != 120 {
+		t.Errorf("expected most recent error as sample, got %v", g.Sample)
+	}
+	if g.Fingerprint != Fingerprint(&pb.ErrorLogRequest{ServiceName: "foo.Foo", MethodName: "Get", ErrorCode: 5, LogMessage: "user 99 not found"}) {
+		t.Errorf("fingerprint does not match")
+	}
+
+	groups = tr.List(&pb.ListErrorGroupsRequest{Order: pb.GroupOrder_BY_LAST_SEEN}, 2)
+	if len(groups) != 2 || groups[0].ServiceName != "bar.Bar" || groups[1].LastSeen != 120 {
+		t.Errorf("wrong order by last seen: %v", groups)
+	}
+	groups = tr.List(&pb.ListErrorGroupsRequest{Services: []string{"BAR"}}, 0)
+	if len(groups) != 1 || groups[0].ServiceName != "bar.Bar" {
+		t.Errorf("wrong groups for service filter: %v", groups)
+	}
+
+	// persisted
+	fname := filepath.Join(t.TempDir(), "issues.pb")
+	err := tr.Save(fname)
+	if err != nil {
+		t.Fatalf("failed to save: %s", err)
+	}
+	tr2 := &Tracker{}
+	err = tr2.Load(fname)
+	if err != nil {
+		t.Fatalf("failed to load: %s", err)
+	}
+	tr2.Add(newLog("foo.Foo", "Get", 5, "user 3 not found", "1", 140))
+	groups = tr2.List(&pb.ListErrorGroupsRequest{Order: pb.GroupOrder_BY_COUNT}, 1)
+	if groups[0].Count != 4 || groups[0].FirstSeen != 100 || len(groups[0].UserIDs) != 2 {
+		t.Errorf("wrong aggregates after loading: %v", groups[0])
+	}
+
+	// bounded
+	tr = &Tracker{MaxGroups: 2}
+	tr.Add(newLog("a", "a", 1, "", "", 100))
+	tr.Add(newLog("b", "b", 1, "", "", 200))
+	tr.Add(newLog("c", "c", 1, "", "", 300))
+	groups = tr.List(&pb.ListErrorGroupsRequest{}, 0)
+	if len(groups) != 2 || groups[1].ServiceName != "b" {
+		t.Errorf("expected least recently seen group to be forgotten: %v", groups)
+	}
+	tr.Add(newLog("b", "b", 1, "", "", 400))
+	tr.Add(newLog("d", "d", 1, "", "", 500))
+	groups = tr.List(&pb.ListErrorGroupsRequest{}, 0)
+	if len(groups) != 2 || groups[0].ServiceName != "d" || groups[1].ServiceName != "b" {
+		t.Errorf("expected the group seen again to be kept: %v", groups)
+	}
+}
+
+func TestGroupState(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tr := &Tracker{now: func() time.Time { return now }}
+	tr.Add(newLog("foo.Foo", "Get", 5, "user 1 not found", "1", 100))
+	tr.Add(newLog("bar.Bar", "Put", 13, "disk full", "", 100))
+	fp := Fingerprint(&pb.ErrorLogRequest{ServiceName: "foo.Foo", MethodName: "Get", ErrorCode: 5, LogMessage: "user 1 not found"})
+
+	_, err := tr.SetState(&pb.SetGroupStateRequest{Fingerprint: "", State: pb.GroupState_RESOLVED})
+	if err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for empty fingerprint, got %v", err)
+	}
+	_, err = tr.SetState(&pb.SetGroupStateRequest{Fingerprint: "nosuchgroup", State: pb.GroupState_RESOLVED})
+	if err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	// resolved groups regress
+	eg, err := tr.SetState(&pb.SetGroupStateRequest{Fingerprint: fp[:8], State: pb.GroupState_RESOLVED})
+	if err != nil {
+		t.Fatalf("failed to resolve: %s", err)
+	}
+	if eg.State != pb.GroupState_RESOLVED || eg.StateChanged != 1000 {
+		t.Errorf("wrong state after resolving: %v", eg)
+	}
+	if tr.Add(newLog("foo.Foo", "Get", 5, "user 2 not found", "2", 1100)).Muted {
+		t.Errorf("resolved group reported as muted")
+	}
+	groups := tr.List(&pb.ListErrorGroupsRequest{States: []pb.GroupState{pb.GroupState_REGRESSED}}, 0)
+	if len(groups) != 1 || groups[0].Fingerprint != fp || groups[0].StateChanged != 1100 {
+		t.Errorf("expected group to be regressed: %v", groups)
+	}
+
+	// muted for 2 more occurrences
+	_, err = tr.SetState(&pb.SetGroupStateRequest{Fingerprint: fp, State: pb.GroupState_MUTED, MuteOccurrences: 2})
+	if err != nil {
+		t.Fatalf("failed to mute: %s", err)
+	}
+	for i, expected := range []bool{true, true, false, false} {
+		if tr.Add(newLog("foo.Foo", "Get", 5, "user 1 not found", "1", 1200)).Muted != expected {
+			t.Errorf("occurrence %d: expected muted=%v", i, expected)
+		}
+	}
+
+	// muted until a time
+	_, err = tr.SetState(&pb.SetGroupStateRequest{Fingerprint: fp, State: pb.GroupState_MUTED, MuteUntil: 2000})
+	if err != nil {
+		t.Fatalf("failed to mute: %s", err)
+	}
+	if !tr.Add(newLog("foo.Foo", "Get", 5, "user 1 not found", "1", 1999)).Muted {
+		t.Errorf("expected group to be muted before MuteUntil")
+	}
+	if tr.Add(newLog("foo.Foo", "Get", 5, "user 1 not found", "1", 2000)).Muted {
+		t.Errorf("expected group to be unmuted at MuteUntil")
+	}
+	// expiry is visible in List() without new errors
+	tr.SetState(&pb.SetGroupStateRequest{Fingerprint: fp, State: pb.GroupState_MUTED, MuteUntil: 3000})
+	now = time.Unix(3000, 0)
+	groups = tr.List(&pb.ListErrorGroupsRequest{States: []pb.GroupState{pb.GroupState_MUTED}}, 0)
+	if len(groups) != 0 {
+		t.Errorf("expected mute to have expired: %v", groups)
+	}
+
+	// muted indefinitely, persisted
+	tr.SetState(&pb.SetGroupStateRequest{Fingerprint: fp, State: pb.GroupState_MUTED})
+	tr.SetState(&pb.SetGroupStateRequest{Fingerprint: Fingerprint(&pb.ErrorLogRequest{ServiceName: "bar.Bar", MethodName: "Put", ErrorCode: 13, LogMessage: "disk full"}), State: pb.GroupState_ACKNOWLEDGED})
+	fname := filepath.Join(t.TempDir(), "issues.pb")
+	err = tr.Save(fname)
+	if err != nil {
+		t.Fatalf("failed to save: %s", err)
+	}
+	tr2 := &Tracker{}
+	err = tr2.Load(fname)
+	if err != nil {
+		t.Fatalf("failed to load: %s", err)
+	}
+	if !tr2.Add(newLog("foo.Foo", "Get", 5, "user 1 not found", "1", 5000)).Muted {
+		t.Errorf("expected group to stay muted after loading")
+	}
+	groups = tr2.List(&pb.ListErrorGroupsRequest{Services: []string{"bar"}}, 0)
+	if len(groups) != 1 || groups[0].State != pb.GroupState_ACKNOWLEDGED {
+		t.Errorf("expected acknowledged group after loading: %v", groups)
+	}
+}
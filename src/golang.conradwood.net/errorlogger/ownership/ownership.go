@@ -0,0 +1,152 @@
+/*
+maps service names to the teams owning them. for example:
+
+	teams:
+	  - team: payments
+	    services: ["payment.*", "billing.BillingService"]
+	    contact: payments@example.com
+	    escalation: "#payments-oncall"
+
+if several entries match a service, the first one wins
+*/
+package ownership
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"golang.conradwood.net/go-easyops/utils"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	max_cached = 10000
+)
+
+type Owner struct {
+	Team       string   `yaml:"team"`
+	Services   []string `yaml:"services"` // case-insensitive patterns of service names, see Match()
+	Contact    string   `yaml:"contact"`  // email address
+	Escalation string   `yaml:"escalation"`
+}
+
+type ownerFile struct {
+	Teams []*Owner `yaml:"teams"`
+}
+
+// the ownership table. safe for concurrent use, the owners may be replaced at any time
+type Table struct {
+	lock   sync.Mutex
+	owners []*Owner
+	cache  map[string]*Owner // by service name. nil values for services without owner
+}
+
+// read owners from a yaml file
+func Load(filename string) ([]*Owner, error) {
+	bs, err := utils.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := Parse(bs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return owners, nil
+}
+
+// parse and check owners in yaml
+func Parse(bs []byte) ([]*Owner, error) {
+	of := &ownerFile{}
+	err := yaml.UnmarshalStrict(bs, of)
+	if err != nil {
+		return nil, err
+	}
+	teams := make(map[string]bool)
+	for i, o := range of.Teams {
+		if o.Team == "" || len(o.Services) == 0 {
+			return nil, fmt.Errorf("entry #%d needs a team and services", i+1)
+		}
+		if teams[o.Team] {
+			return nil, fmt.Errorf("duplicate team \"%s\"", o.Team)
+		}
+		teams[o.Team] = true
+		for _, p := range o.Services {
+			err = ValidPattern(p)
+			if err != nil {
+				return nil, fmt.Errorf("team \"%s\": %s", o.Team, err)
+			}
+		}
+	}
+	return of.Teams, nil
+}
+
+// replace the owners
+func (t *Table) Set(owners []*Owner) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.owners = owners
+	t.cache = make(map[string]*Owner)
+}
+
+// the owner of a service. nil if it has none
+func (t *Table) Lookup(service string) *Owner {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if len(t.owners) == 0 {
+		return nil
+	}
+	o, ok := t.cache[service]
+	if ok {
+		return o
+	}
+	for _, x := range t.owners {
+		if Match(x.Services, service) {
+			o = x
+			break
+		}
+	}
+	if len(t.cache) >= max_cached {
+		// service names come from clients, do not let them grow the cache forever
+		t.cache = make(map[string]*Owner)
+	}
+	t.cache[service] = o
+	return o
+}
+
+// the team owning a service. "" if it has none
+func (t *Table) Team(service string) string {
+	o := t.Lookup(service)
+	if o == nil {
+		return ""
+	}
+	return o.Team
+}
+
+// all owners, in the order of the table
+func (t *Table) Owners() []*Owner {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.owners
+}
+
+// an error if the pattern is not valid for Match()
+func ValidPattern(pattern string) error {
+	_, err := path.Match(pattern, "")
+	if err != nil {
+		return fmt.Errorf("invalid pattern \"%s\": %s", pattern, err)
+	}
+	return nil
+}
+
+// true if the service matches any of the patterns (as in path.Match, case-insensitive)
+func Match(patterns []string, service string) bool {
+	service = strings.ToLower(service)
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToLower(p), service); ok {
+			return true
+		}
+	}
+	return false
+}
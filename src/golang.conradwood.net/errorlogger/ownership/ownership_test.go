@@ -0,0 +1,57 @@
+package ownership
+
+import (
+	"testing"
+)
+
+const test_table = `
+teams:
+  - team: payments
+    services: ["payment.*", "billing.BillingService"]
+    contact: payments@example.com
+    escalation: "#payments-oncall"
+  - team: platform
+    services: ["*"]
+    contact: platform@example.com
+`
+
+func TestTable(t *testing.T) {
+	owners, err := Parse([]byte(test_table))
+	if err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+	tb := &Table{}
+	if tb.Team("payment.PaymentService") != "" {
+		t.Errorf("empty table returned a team")
+	}
+	tb.Set(owners)
+	for service, expected := range map[string]string{
+		"payment.PaymentService": "payments",
+		"Billing.billingservice": "payments",
+		"billing.Other":          "platform",
+	} {
+		for i := 0; i < 2; i++ { // cached
+			got := tb.Team(service)
+			if got != expected {
+				t.Errorf("%s: expected team \"%s\", got \"%s\"", service, expected, got)
+			}
+		}
+	}
+	if o := tb.Lookup("payment.X"); o == nil || o.Escalation != "#payments-oncall" || o.Contact != "payments@example.com" {
+		t.Errorf("wrong owner: %v", o)
+	}
+	tb.Set(owners[:1])
+	if tb.Team("billing.Other") != "" {
+		t.Errorf("lookup cached across Set()")
+	}
+	for _, bad := range []string{
+		"teams:\n  - services: [foo]\n",
+		"teams:\n  - team: a\n    services: [a]\n  - team: a\n    services: [b]\n",
+		"teams:\n  - team: a\n    services: [\"[\"]\n",
+	} {
+		_, err := Parse([]byte(bad))
+		if err == nil {
+			t.Errorf("invalid table accepted: %s", bad)
+		}
+	}
+}
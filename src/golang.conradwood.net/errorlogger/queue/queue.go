@@ -0,0 +1,145 @@
+/*
+a bounded in-process queue. Producers add items, a single worker hands them in batches to a flush function.
+what happens if the queue is full depends on the Policy.
+*/
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	ErrDropped = errors.New("queue is full, item dropped")
+	ErrClosed  = errors.New("queue is closed")
+)
+
+// what Add() does if the queue is full
+type Policy int
+
+const (
+	POLICY_BLOCK       Policy = iota // wait until there is space
+	POLICY_DROP_OLDEST               // drop the oldest queued item
+	POLICY_DROP_NEWEST               // drop the item being added
+)
+
+// parse "block", "drop-oldest" or "drop-newest"
+func ParsePolicy(s string) (Policy, error) {
+	switch s {
+	case "", "block":
+		return POLICY_BLOCK, nil
+	case "drop-oldest":
+		return POLICY_DROP_OLDEST, nil
+	case "drop-newest":
+		return POLICY_DROP_NEWEST, nil
+	}
+	return POLICY_BLOCK, fmt.Errorf("invalid queue policy \"%s\" (valid: block, drop-oldest, drop-newest)", s)
+}
+
+type Queue struct {
+	Size      int               // how many items may be queued. defaults to 1000
+	BatchSize int               // maximum number of items passed to Flush at once. defaults to 100
+	Policy    Policy            // what to do if the queue is full
+	Flush     func(batch []any) // called by the worker with the oldest queued items
+	OnDrop    func(item any)    // optional, called for every dropped item. must not block
+	lock      sync.Mutex
+	not_empty *sync.Cond
+	not_full  *sync.Cond
+	items     []any
+	closed    bool
+	start     sync.Once
+	done      chan struct{}
+}
+
+func (q *Queue) init() {
+	q.start.Do(func() {
+		if q.Size <= 0 {
+			q.Size = 1000
+		}
+		if q.BatchSize <= 0 {
+			q.BatchSize = 100
+		}
+		q.not_empty = sync.NewCond(&q.lock)
+		q.not_full = sync.NewCond(&q.lock)
+		q.done = make(chan struct{})
+		go q.worker()
+	})
+}
+
+// queue an item. returns ErrDropped if the policy dropped it (not if it dropped another item) and ErrClosed after Close()
+func (q *Queue) Add(item any) error {
+	q.init()
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for q.Policy == POLICY_BLOCK && len(q.items) >= q.Size && !q.closed {
+		q.not_full.Wait()
+	}
+	if q.closed {
+		return ErrClosed
+	}
+	if len(q.items) >= q.Size {
+		if q.Policy == POLICY_DROP_NEWEST {
+			q.dropped(item)
+			return ErrDropped
+		}
+		q.dropped(q.items[0])
+		q.items[0] = nil
+		q.items = q.items[1:]
+	}
+	q.items = append(q.items, item)
+	q.not_empty.Signal()
+	return nil
+}
+
+func (q *Queue) dropped(item any) {
+	if q.OnDrop != nil {
+		q.OnDrop(item)
+	}
+}
+
+// the number of queued items, not including those being flushed
+func (q *Queue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.items)
+}
+
+// stop accepting items and wait until all queued items are flushed
+func (q *Queue) Close() {
+	q.init()
+	q.lock.Lock()
+	q.closed = true
+	q.not_empty.Broadcast()
+	q.not_full.Broadcast()
+	q.lock.Unlock()
+	<-q.done
+}
+
+func (q *Queue) worker() {
+	for {
+		q.lock.Lock()
+		for len(q.items) == 0 && !q.closed {
+			q.not_empty.Wait()
+		}
+		if len(q.items) == 0 {
+			// closed and drained
+			q.lock.Unlock()
+			close(q.done)
+			return
+		}
+		n := len(q.items)
+		if n > q.BatchSize {
+			n = q.BatchSize
+		}
+		batch := make([]any, n)
+		copy(batch, q.items)
+		for i := 0; i < n; i++ {
+			q.items[i] = nil
+		}
+		q.items = q.items[n:]
+		q.not_full.Broadcast()
+		q.lock.Unlock()
+		q.Flush(batch)
+	}
+}
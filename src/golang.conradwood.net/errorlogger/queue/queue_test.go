@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// a queue whose worker waits for "release" before it flushes
+func blockedQueue(policy Policy, size int) (*Queue, chan bool, *[]int, *int) {
+	release := make(chan bool)
+	var flushed []int
+	dropped := 0
+	q := &Queue{Size: size, BatchSize: 2, Policy: policy}
+	q.Flush = func(batch []any) {
+		<-release
+		for _, b := range batch {
+			flushed = append(flushed, b.(int))
+		}
+	}
+	q.OnDrop = func(item any) {
+		dropped++
+	}
+	return q, release, &flushed, &dropped
+}
+
+// add an item and wait until the worker has taken it out of the queue
+func addAndTake(q *Queue, item int) {
+	q.Add(item)
+	for q.Len() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDropPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy   Policy
+		expected []int
+	}{
+		{POLICY_DROP_OLDEST, []int{0, 3, 4, 5}},
+		{POLICY_DROP_NEWEST, []int{0, 1, 2, 3}},
+	} {
+		q, release, flushed, dropped := blockedQueue(tc.policy, 3)
+		// the worker holds item 0 and waits, 1-5 are queued in a queue of size 3
+		addAndTake(q, 0)
+		for i := 1; i <= 5; i++ {
+			q.Add(i)
+		}
+		if q.Len() != 3 {
+			t.Errorf("policy %d: expected 3 queued items, got %d", tc.policy, q.Len())
+		}
+		go func() {
+			for {
+				release <- true
+			}
+		}()
+		q.Close()
+		if *dropped != 2 {
+			t.Errorf("policy %d: expected 2 dropped items, got %d", tc.policy, *dropped)
+		}
+		if len(*flushed) != len(tc.expected) {
+			t.Fatalf("policy %d: expected %v to be flushed, got %v", tc.policy, tc.expected, *flushed)
+		}
+		for i, e := range tc.expected {
+			if (*flushed)[i] != e {
+				t.Errorf("policy %d: expected %v to be flushed, got %v", tc.policy, tc.expected, *flushed)
+				break
+			}
+		}
+		if q.Add(6) != ErrClosed {
+			t.Errorf("policy %d: closed queue accepted an item", tc.policy)
+		}
+	}
+}
+
+func TestBlockPolicy(t *testing.T) {
+	var lock sync.Mutex
+	var flushed []int
+	q := &Queue{Size: 5, BatchSize: 3, Policy: POLICY_BLOCK}
+	q.Flush = func(batch []any) {
+		if len(batch) > 3 {
+			t.Errorf("batch of %d items", len(batch))
+		}
+		lock.Lock()
+		for _, b := range batch {
+			flushed = append(flushed, b.(int))
+		}
+		lock.Unlock()
+	}
+	q.OnDrop = func(item any) {
+		t.Errorf("item %v dropped", item)
+	}
+	var wg sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < 250; i++ {
+				q.Add(p*1000 + i)
+			}
+		}(p)
+	}
+	wg.Wait()
+	q.Close()
+	if len(flushed) != 1000 {
+		t.Fatalf("expected 1000 items to be flushed, got %d", len(flushed))
+	}
+	// each producer's items are flushed in order
+	last := make(map[int]int)
+	for _, f := range flushed {
+		p := f / 1000
+		if n, ok := last[p]; ok && f%1000 != n+1 {
+			t.Fatalf("producer %d: item %d flushed after item %d", p, f%1000, n)
+		}
+		last[p] = f % 1000
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"golang.conradwood.net/apis/common"
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/errorlogger/alerting"
+	"golang.conradwood.net/go-easyops/prometheus"
+)
+
+var (
+	alert_rules    = flag.String("alert_rules", "", "yaml `file` with alert rules. it is reloaded when it changes. no alerts if empty")
+	alertEngine    = &alerting.Engine{OnAlert: raiseAlert}
+	alertRulesFile = &configFile{filename: alert_rules, what: "alert rules", load: loadAlertRules}
+)
+
+func (e *echoServer) GetAlerts(ctx context.Context, req *common.Void) (*pb.AlertList, error) {
+	return &pb.AlertList{Rules: alertEngine.State(uint32(time.Now().Unix()))}, nil
+}
+
+func raiseAlert(a *pb.Alert) {
+	alertCounter.With(prometheus.Labels{"rule": a.Rule}).Inc()
+	msg := ""
+	if a.Log != nil && a.Log.Err != nil {
+		e := a.Log.Err
+		msg = fmt.Sprintf("%s.%s: %s", e.ServiceName, e.MethodName, e.LogMessage)
+	}
+	fmt.Printf("ALERT %s (%d errors, %d suppressed): %s\n", a.Rule, a.Count, a.Suppressed, msg)
+	notifyAlert(a)
+}
+
+func loadAlertRules(filename string) error {
+	rules, err := alerting.LoadRules(filename)
+	if err != nil {
+		return err
+	}
+	alertEngine.SetRules(rules)
+	fmt.Printf("Loaded %d alert rules from %s\n", len(rules), filename)
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/errorlogger/anomaly"
+	"golang.conradwood.net/go-easyops/prometheus"
+)
+
+var (
+	anomaly_interval  = flag.Duration("anomaly_interval", time.Minute, "errors are counted per interval of this length to detect spikes. baselines saved with a different interval are discarded")
+	anomaly_threshold = flag.Float64("anomaly_threshold", 4, "an error rate this many deviations above its baseline is an anomaly")
+	anomaly_min_count = flag.Uint64("anomaly_min_count", 10, "minimum errors in an interval for an anomaly")
+	anomalyDetector   = &anomaly.Detector{OnAnomaly: raiseAnomaly}
+)
+
+const (
+	anomaly_tick = 10 * time.Second // how often intervals are closed and the gauges are updated
+)
+
+func (e *echoServer) GetAnomalies(ctx context.Context, req *pb.GetAnomaliesRequest) (*pb.AnomalyList, error) {
+	return &pb.AnomalyList{Anomalies: anomalyDetector.List(req)}, nil
+}
+
+func raiseAnomaly(a *pb.Anomaly) {
+	fmt.Printf("ANOMALY %s/%s: %d errors in the current interval, baseline %.1f±%.1f\n", a.ServiceName, a.MethodName, a.Count, a.Baseline, a.Deviation)
+	anomalyActive.With(prometheus.Labels{"servicename": a.ServiceName, "method": a.MethodName}).Set(1)
+	notifyAnomaly(a)
+}
+
+func baselinesFilename() string {
+	return fmt.Sprintf("%s/baselines.pb", *logdir)
+}
+
+// load the baselines saved by a previous run
+func loadBaselines() error {
+	anomalyDetector.Interval = *anomaly_interval
+	anomalyDetector.Threshold = *anomaly_threshold
+	anomalyDetector.MinCount = *anomaly_min_count
+	return anomalyDetector.Load(baselinesFilename())
+}
+
+// close intervals without errors, update the gauges and save the baselines periodically
+func tickAnomalies() {
+	last_save := time.Now()
+	exported := make(map[[2]string]bool) // service and method of the gauges set
+	for {
+		time.Sleep(anomaly_tick)
+		anomalyDetector.Tick(uint32(time.Now().Unix()))
+		current := make(map[[2]string]bool)
+		for _, a := range anomalyDetector.List(&pb.GetAnomaliesRequest{All: true}) {
+			current[[2]string{a.ServiceName, a.MethodName}] = true
+			l := prometheus.Labels{"servicename": a.ServiceName, "method": a.MethodName}
+			anomalyScore.With(l).Set(a.Score)
+			anomalyBaseline.With(l).Set(a.Baseline)
+			active := 0.0
+			if a.Active {
+				active = 1
+			}
+			anomalyActive.With(l).Set(active)
+		}
+		// baselines the detector forgot
+		for k := range exported {
+			if current[k] {
+				continue
+			}
+			l := prometheus.Labels{"servicename": k[0], "method": k[1]}
+			anomalyScore.Delete(l)
+			anomalyBaseline.Delete(l)
+			anomalyActive.Delete(l)
+		}
+		exported = current
+		if time.Since(last_save) >= save_interval {
+			saveBaselines()
+			last_save = time.Now()
+		}
+	}
+}
+
+func saveBaselines() error {
+	err := anomalyDetector.Save(baselinesFilename())
+	if err != nil {
+		fmt.Printf("Failed to save error rate baselines: %s\n", err)
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	config_reload = flag.Duration("config_reload", 10*time.Second, "how often to check -alert_rules and -ownership for changes")
+	config_files  []*configFile // reloaded on SIGHUP
+)
+
+// a config file which is reloaded when it changes
+type configFile struct {
+	filename *string // nothing is loaded if empty
+	what     string  // for messages, e.g. "alert rules"
+	load     func(filename string) error
+	lock     sync.Mutex
+	modtime  time.Time
+}
+
+// load the file and reload it whenever it changes
+func (c *configFile) Start() error {
+	if *c.filename == "" {
+		return nil
+	}
+	err := c.Load()
+	if err != nil {
+		return err
+	}
+	config_files = append(config_files, c)
+	go c.watch()
+	return nil
+}
+
+func (c *configFile) Load() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	st, err := os.Stat(*c.filename)
+	if err != nil {
+		return err
+	}
+	err = c.load(*c.filename)
+	if err != nil {
+		return err
+	}
+	c.modtime = st.ModTime()
+	return nil
+}
+
+func (c *configFile) watch() {
+	for {
+		time.Sleep(*config_reload)
+		st, err := os.Stat(*c.filename)
+		if err != nil {
+			fmt.Printf("Failed to check %s: %s\n", c.what, err)
+			continue
+		}
+		c.lock.Lock()
+		changed := !st.ModTime().Equal(c.modtime)
+		c.lock.Unlock()
+		if changed {
+			c.Reload()
+		}
+	}
+}
+
+// reload the file. if it is broken the error is reported and the previous configuration is kept
+func (c *configFile) Reload() {
+	err := c.Load()
+	if err != nil {
+		fmt.Printf("Failed to reload %s, keeping the previous ones: %s\n", c.what, err)
+	}
+}
+
+func reloadConfigFiles() {
+	for _, c := range config_files {
+		c.Reload()
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/errorlogger/digest"
+)
+
+var (
+	digest_interval = flag.Duration("digest_interval", 0, "email a digest of the errors of each interval (e.g. 1h or 24h) to the owners of the services. 0 disables digests")
+	digest_owners   = flag.String("digest_owners", "", "yaml `file` mapping service name patterns to owner email addresses. read for each digest. if empty, digests are sent to the contacts in -ownership")
+	digest_from     = flag.String("digest_from", "errorlogger@localhost", "sender address of digests")
+	smtp_relay      = flag.String("smtp_relay", "localhost:25", "SMTP relay (host:port) to send digests through")
+	smtp_user       = flag.String("smtp_user", "", "if set, authenticate to the SMTP relay with this username and -smtp_password")
+	smtp_password   = flag.String("smtp_password", "", "password for -smtp_user")
+)
+
+// check the digest configuration and start sending digests
+func startDigests() error {
+	if *digest_interval == 0 {
+		return nil
+	}
+	if *digest_owners == "" && *ownership_file == "" {
+		return fmt.Errorf("-digest_interval requires -digest_owners or -ownership")
+	}
+	_, err := digestOwners()
+	if err != nil {
+		return err
+	}
+	go sendDigestsPeriodically()
+	return nil
+}
+
+// send digests at the end of each interval (e.g. on the hour with 1h)
+func sendDigestsPeriodically() {
+	for {
+		next := time.Now().Truncate(*digest_interval).Add(*digest_interval)
+		time.Sleep(time.Until(next))
+		err := sendDigests(next.Add(-*digest_interval), next)
+		if err != nil {
+			fmt.Printf("Failed to send digests: %s\n", err)
+		}
+	}
+}
+
+// send the digests of the errors received from start up to (not including) end
+func sendDigests(start, end time.Time) error {
+	owners, err := digestOwners()
+	if err != nil {
+		return err
+	}
+	s := uint32(start.Unix())
+	e := uint32(end.Unix()) - 1
+	b := digest.NewBuilder(owners, s, e)
+	err = readTimeRange(s, e, func(pl *pb.ProtoLog) error {
+		b.Add(pl)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	b.AddGroups(issueTracker.List(&pb.ListErrorGroupsRequest{}, 0))
+	digests := b.Digests()
+	m := &digest.Mailer{Relay: *smtp_relay, From: *digest_from}
+	if *smtp_user != "" {
+		host, _, err := net.SplitHostPort(*smtp_relay)
+		if err != nil {
+			return err
+		}
+		m.Auth = smtp.PlainAuth("", *smtp_user, *smtp_password, host)
+	}
+	fmt.Printf("Sending %d digests\n", len(digests))
+	return m.SendDigests(digests)
+}
+
+// the recipients of digests: -digest_owners, or the contacts of the teams in the ownership table
+func digestOwners() ([]*digest.Owner, error) {
+	if *digest_owners != "" {
+		return digest.LoadOwners(*digest_owners)
+	}
+	var res []*digest.Owner
+	for _, o := range ownershipTable.Owners() {
+		if o.Contact == "" {
+			continue
+		}
+		res = append(res, &digest.Owner{Services: o.Services, Emails: []string{o.Contact}})
+	}
+	return res, nil
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"flag"
 	"fmt"
@@ -9,41 +8,149 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	apb "golang.conradwood.net/apis/auth"
 	"golang.conradwood.net/apis/common"
 	pb "golang.conradwood.net/apis/errorlogger"
 	"golang.conradwood.net/errorlogger/broadcaster"
+	"golang.conradwood.net/errorlogger/dedup"
 	"golang.conradwood.net/errorlogger/filelogger"
+	"golang.conradwood.net/errorlogger/queue"
 	"golang.conradwood.net/errorlogger/streamblock"
-	"golang.conradwood.net/go-easyops/auth"
+	"golang.conradwood.net/errorlogger/usercache"
 	"golang.conradwood.net/go-easyops/authremote"
 	"golang.conradwood.net/go-easyops/prometheus"
 	"golang.conradwood.net/go-easyops/server"
 	"golang.conradwood.net/go-easyops/utils"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var (
-	debug        = flag.Bool("debug", false, "debug mode")
-	errorCounter = prometheus.NewCounterVec(
+	debug            = flag.Bool("debug", false, "debug mode")
+	proto_checksums  = flag.Bool("proto_checksums", true, "write a checksum with each entry in proto.log")
+	use_proto_index  = flag.Bool("proto_index", true, "maintain an index of proto.log to speed up ReadLog()")
+	max_logs_to_send = flag.Int("max_logs_to_send", 1000, "maximum number of logs a ReadLog() call may request before going to real-time")
+	proto_segment_mb = flag.Int("proto_segment_mb", 100, "start a new proto.log segment once the current one reaches this size (in megabytes). 0 disables segmentation")
+	proto_segments   = flag.Int("proto_segments", 0, "number of old proto.log segments to keep. 0 keeps all")
+	proto_max_age    = flag.Duration("proto_max_age", 0, "delete old proto.log segments last written to longer ago than this. 0 keeps them")
+	sync_all         = flag.String("sync_all", "none", "when to fsync all.log: none (leave it to the OS), always (after every write) or an interval such as 500ms")
+	sync_small       = flag.String("sync_small", "none", "when to fsync small.log, see -sync_all")
+	sync_users       = flag.String("sync_users", "none", "when to fsync users.log and the per-user logfiles, see -sync_all")
+	sync_proto       = flag.String("sync_proto", "always", "when to fsync proto.log, see -sync_all")
+	user_cache_ttl   = flag.Duration("user_cache_ttl", 10*time.Minute, "how long user records are cached")
+	user_timeout     = flag.Duration("user_timeout", 200*time.Millisecond, "how long to wait for a user lookup before logging without the user's email")
+	max_user_logs    = flag.Int("max_user_logs", 100, "maximum number of per-user logfiles to keep open")
+	user_log_idle    = flag.Duration("user_log_idle", 5*time.Minute, "close per-user logfiles not written to for this long")
+	dedup_window     = flag.Duration("dedup_window", 10*time.Minute, "how long idempotency keys are remembered to recognise retried logs")
+	dedup_max        = flag.Int("dedup_max", 100000, "maximum number of idempotency keys to remember")
+	queue_size       = flag.Int("queue_size", 10000, "maximum number of logs queued for writing")
+	queue_batch      = flag.Int("queue_batch", 100, "maximum number of logs written to the logfiles at once")
+	queue_policy     = flag.String("queue_policy", "block", "what to do if the queue is full: block the caller, drop-oldest or drop-newest")
+	errorCounter     = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "errorlogger_errors_received",
 			Help: "V=1 UNIT=none DESC=logs errors received",
 		},
 		[]string{"grpccode", "servicename", "method"},
 	)
+	corruptCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "errorlogger_corrupt_protologs",
+			Help: "V=1 UNIT=none DESC=damaged entries skipped while reading proto.log",
+		},
+		[]string{"reason"},
+	)
+	queueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "errorlogger_queue_depth",
+			Help: "V=1 UNIT=none DESC=logs queued for writing",
+		},
+	)
+	duplicateCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "errorlogger_duplicates",
+			Help: "V=1 UNIT=none DESC=retried logs which were not logged again",
+		},
+	)
+	userCacheCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "errorlogger_usercache_lookups",
+			Help: "V=1 UNIT=none DESC=user lookups by result (hit, negative_hit, miss, timeout, error)",
+		},
+		[]string{"result"},
+	)
+	queueDropped = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "errorlogger_queue_dropped",
+			Help: "V=1 UNIT=none DESC=logs dropped because the queue was full",
+		},
+	)
+	listenerOverflows = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "errorlogger_listener_overflows",
+			Help: "V=1 UNIT=none DESC=ReadLog() streams ended because the client fell behind",
+		},
+	)
+	anomalyScore = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "errorlogger_anomaly_score",
+			Help: "V=1 UNIT=none DESC=deviations the error rate of the current interval is above its baseline (method empty for the whole service)",
+		},
+		[]string{"servicename", "method"},
+	)
+	anomalyActive = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "errorlogger_anomaly_active",
+			Help: "V=1 UNIT=none DESC=1 if the error rate is significantly above its baseline",
+		},
+		[]string{"servicename", "method"},
+	)
+	anomalyBaseline = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "errorlogger_error_rate_baseline",
+			Help: "V=1 UNIT=none DESC=baseline of errors per interval",
+		},
+		[]string{"servicename", "method"},
+	)
+	alertCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "errorlogger_alerts",
+			Help: "V=1 UNIT=none DESC=alerts raised by rule",
+		},
+		[]string{"rule"},
+	)
+
+	protolock       sync.Mutex // serialises writes to protolog with the broadcast and with the start of a ReadLog() replay
+	port            = flag.Int("port", 4100, "The grpc server port")
+	logdir          = flag.String("logdir", "/var/log/errorlogger", "`directory` of errors log")
+	log_max_mb      = flag.Int("log_max_mb", 100, "rotate text logfiles once they reach this size (in megabytes). 0 disables rotation")
+	log_generations = flag.Int("log_generations", 5, "number of rotated text logfiles to keep. With -log_rotate 0 keeps all")
+	log_rotate      = flag.String("log_rotate", "none", "also rotate text logfiles at the start of each period (none, daily or hourly)")
+	log_compress    = flag.Bool("log_compress", false, "gzip rotated text logfiles")
+	log_max_age     = flag.Duration("log_max_age", 0, "delete rotated text logfiles older than this. 0 keeps them")
+	logger          *filelogger.FileLogger
+	smallLogger     *filelogger.FileLogger
+	userlog         *filelogger.FileLogger
+	protolog        *streamblock.SegmentedWriter
+	userLogs        = &filelogger.Pool{}
+	logBroadcaster  = &broadcaster.Broadcaster{QueueSize: 1000}
+	logQueue        *queue.Queue
+	recentKeys      = &dedup.Set{}
+	userCache       = &usercache.Cache{
+		Lookup: func(userid string) (*apb.User, error) {
+			return authremote.GetUserByID(authremote.Context(), userid)
+		},
+		Observe: func(r usercache.Result) {
+			userCacheCounter.With(prometheus.Labels{"result": r.String()}).Inc()
+		},
+	}
+)
 
-	userlock       sync.Mutex
-	port           = flag.Int("port", 4100, "The grpc server port")
-	logdir         = flag.String("logdir", "/var/log/errorlogger", "`directory` of errors log")
-	logger         *filelogger.FileLogger
-	smallLogger    *filelogger.FileLogger
-	userlog        *filelogger.FileLogger
-	protolog       io.Writer
-	peruserlog     = make(map[string]*filelogger.FileLogger)
-	logBroadcaster = &broadcaster.Broadcaster{}
+const (
+	default_logs_to_send = 100 // if ReadLogRequest does not specify how many
 )
 
 type echoServer struct {
@@ -53,17 +160,50 @@ func main() {
 	flag.Parse()
 	server.SetHealth(common.Health_STARTING)
 	fmt.Printf("Starting ErrorLoggerServer...\n")
-	prometheus.MustRegister(errorCounter)
+	prometheus.MustRegister(errorCounter, corruptCounter, queueDepth, queueDropped, userCacheCounter, duplicateCounter, alertCounter, listenerOverflows,
+		anomalyScore, anomalyActive, anomalyBaseline)
 	var err error
-	logger, err = filelogger.Open(fmt.Sprintf("%s/all.log", *logdir))
+	logger, err = filelogger.Open(fmt.Sprintf("%s/all.log", *logdir), textLogOptions(*sync_all))
 	utils.Bail("failed to open logfile", err)
-	smallLogger, err = filelogger.Open(fmt.Sprintf("%s/small.log", *logdir))
+	smallLogger, err = filelogger.Open(fmt.Sprintf("%s/small.log", *logdir), textLogOptions(*sync_small))
 	utils.Bail("failed to open logfile", err)
-	userlog, err = filelogger.Open(fmt.Sprintf("%s/users.log", *logdir))
+	userlog, err = filelogger.Open(fmt.Sprintf("%s/users.log", *logdir), textLogOptions(*sync_users))
 	utils.Bail("failed to open userlogfile", err)
-	fl, err := filelogger.Open(fmt.Sprintf("%s/proto.log", *logdir))
+	protolog, err = streamblock.OpenSegmentedWriter(protologFilename(), protoLogOptions())
 	utils.Bail("failed to open protologfile", err)
-	protolog = streamblock.NewBlockWriter(fl)
+	logBroadcaster.OnOverflow = listenerOverflows.Inc
+	userLogs.Options = textLogOptions(*sync_users)
+	userLogs.MaxOpen = *max_user_logs
+	userLogs.IdleTimeout = *user_log_idle
+	recentKeys.Window = *dedup_window
+	recentKeys.MaxEntries = *dedup_max
+	userCache.TTL = *user_cache_ttl
+	userCache.Timeout = *user_timeout
+	policy, err := queue.ParsePolicy(*queue_policy)
+	utils.Bail("invalid -queue_policy", err)
+	logQueue = &queue.Queue{
+		Size:      *queue_size,
+		BatchSize: *queue_batch,
+		Policy:    policy,
+		Flush:     flushLogs,
+		OnDrop:    droppedLog,
+	}
+	err = loadIssues()
+	utils.Bail("failed to load error groups", err)
+	go saveIssuesPeriodically()
+	err = ownershipFile.Start()
+	utils.Bail("failed to load ownership table", err)
+	err = alertRulesFile.Start()
+	utils.Bail("failed to load alert rules", err)
+	err = startWebhooks()
+	utils.Bail("failed to start webhooks", err)
+	err = startDigests()
+	utils.Bail("failed to start digests", err)
+	err = loadBaselines()
+	utils.Bail("failed to load error rate baselines", err)
+	go tickAnomalies()
+	go reopenOnHangup()
+	go drainOnTerminate()
 
 	sd := server.NewServerDef()
 	sd.SetNoAuth()
@@ -89,184 +229,212 @@ func startup() {
 ************************************/
 
 func (e *echoServer) Log(ctx context.Context, req *pb.ErrorLogRequest) (*common.Void, error) {
-	var err error
-	ctx = authremote.Context()
-	email := ""
-	var user *apb.User
-	if req.UserID != "" {
-		user, err = authremote.GetUserByID(ctx, req.UserID)
-		if err == nil {
-			email = user.Email
-		} else {
-			fmt.Printf("Unable to get user: %s\n", err)
-		}
+	err := logOne(req)
+	if err == queue.ErrClosed {
+		return nil, status.Errorf(codes.Unavailable, "errorlogger is shutting down")
 	}
-	if *debug {
-		fmt.Printf("Service \"%s\", Method \"%s\", code %d\n", req.ServiceName, req.MethodName, req.ErrorCode)
+	if err == queue.ErrDropped {
+		// the caller may retry later
+		return nil, status.Errorf(codes.ResourceExhausted, "errorlogger is overloaded, log dropped")
 	}
-	l := prometheus.Labels{"grpccode": fmt.Sprintf("%d", req.ErrorCode), "servicename": req.ServiceName, "method": req.MethodName}
-	errorCounter.With(l).Inc()
-	storeprotolog(ctx, req)
+	return &common.Void{}, nil
+}
 
-	svcinfo := "unavailable"
-	if req.CallingService != nil {
-		svcinfo = fmt.Sprintf("%s(%s)", req.CallingService.ID, req.CallingService.Email)
+func (e *echoServer) LogBatch(ctx context.Context, req *pb.LogBatchRequest) (*pb.LogBatchResponse, error) {
+	res := &pb.LogBatchResponse{}
+	for i, l := range req.Logs {
+		res.Acks = append(res.Acks, logAck(i, logOne(l)))
 	}
-	var buf bytes.Buffer
-	s := fmt.Sprintf("%s: #%05s(%s) [%s->%s.%s] %s %s\n",
-		utils.TimestampString(req.Timestamp),
-		req.UserID, email,
-		svcinfo,
-		req.ServiceName, req.MethodName,
-		(codes.Code(req.ErrorCode)).String(),
-		req.LogMessage,
-	)
-	buf.WriteString(s)
-	logger.WriteString(buf.String())
-	if req.UserID != "" {
-		userlog.WriteString(buf.String())
-	}
-	if user != nil {
-		fl := getUserLog(user)
-		if fl != nil {
-			fl.WriteString(buf.String())
+	return res, nil
+}
+
+func (e *echoServer) LogStream(srv pb.ErrorLogger_LogStreamServer) error {
+	res := &pb.LogBatchResponse{}
+	for i := 0; ; i++ {
+		l, err := srv.Recv()
+		if err == io.EOF {
+			break
 		}
+		if err != nil {
+			return err
+		}
+		res.Acks = append(res.Acks, logAck(i, logOne(l)))
 	}
-	ec := codes.Code(req.ErrorCode)
-	if ec != codes.NotFound && ec != codes.PermissionDenied && ec != codes.Unauthenticated {
-		smallLogger.WriteString(buf.String())
-	}
-	return &common.Void{}, nil
+	return srv.SendAndClose(res)
 }
 
-func getUserLog(u *apb.User) *filelogger.FileLogger {
-	if u == nil {
+// count and queue a log, unless it was logged already
+func logOne(req *pb.ErrorLogRequest) error {
+	if *debug {
+		fmt.Printf("Service \"%s\", Method \"%s\", code %d\n", req.ServiceName, req.MethodName, req.ErrorCode)
+	}
+	if req.IdempotencyKey != "" && recentKeys.Seen(req.IdempotencyKey) {
+		duplicateCounter.Inc()
 		return nil
 	}
-	fl := peruserlog[u.ID]
-	if fl != nil {
-		return fl
+	l := prometheus.Labels{"grpccode": fmt.Sprintf("%d", req.ErrorCode), "servicename": req.ServiceName, "method": req.MethodName}
+	errorCounter.With(l).Inc()
+	// written to the logfiles by flushLogs()
+	err := logQueue.Add(&queuedLog{req: req, received: uint32(time.Now().Unix())})
+	queueDepth.Set(float64(logQueue.Len()))
+	if err != nil && req.IdempotencyKey != "" {
+		// not logged, so a retry must not be treated as duplicate
+		recentKeys.Forget(req.IdempotencyKey)
+	}
+	return err
+}
+
+// called by the queue for a log it dropped, which may have been accepted already
+func droppedLog(item any) {
+	queueDropped.Inc()
+	if key := item.(*queuedLog).req.IdempotencyKey; key != "" {
+		// not logged, so a retry must not be treated as duplicate
+		recentKeys.Forget(key)
 	}
-	userlock.Lock()
-	defer userlock.Unlock()
-	fl = peruserlog[u.ID]
-	if fl != nil {
-		return fl
+}
+
+// the acknowledgement for entry i of a batch or stream, which logOne() returned err for
+func logAck(i int, err error) *pb.LogAck {
+	if err != nil {
+		return &pb.LogAck{Index: uint32(i), Error: err.Error()}
 	}
+	return &pb.LogAck{Index: uint32(i), Accepted: true}
+}
+
+// the per-user logfile of a user
+func userLogFilename(u *apb.User) string {
 	ua := u.Abbrev
 	if ua == "" {
 		ua = u.ID
 	}
-	s := fmt.Sprintf("%s/%s.log", *logdir, ua)
-	fl, err := filelogger.Open(s)
-	utils.Bail("failed to open logfile", err)
-	peruserlog[u.ID] = fl
-	return fl
+	return fmt.Sprintf("%s/%s.log", *logdir, ua)
 }
-func storeprotolog(ctx context.Context, req *pb.ErrorLogRequest) {
-	pl := &pb.ProtoLog{
-		Err:     req,
-		User:    auth.GetUser(ctx),
-		Service: auth.GetService(ctx),
+func (e *echoServer) ReadLog(req *pb.ReadLogRequest, srv pb.ErrorLogger_ReadLogServer) error {
+	fmt.Printf("Listener added for services \"%s\"\n", strings.Join(req.Services, " "))
+	filter := newLogFilter(req)
+	if req.EndTimestamp != 0 {
+		return sendTimeRange(req, filter, srv)
 	}
-	bs, err := utils.MarshalBytes(pl)
+	// everything written before the reader is opened is replayed from the file, everything after is received by the listener
+	protolock.Lock()
+	listener := logBroadcaster.Register()
+	sr, err := protolog.OpenReader()
+	protolock.Unlock()
 	if err != nil {
-		fmt.Printf("Failed to marshal error proto: %s\n", err)
-		return
+		listener.Close()
+		return err
 	}
-	_, err = protolog.Write(bs)
-	if err != nil {
-		fmt.Printf("failed to write proto: %s\n", err)
-		return
+
+	// send from log
+	history := readHistory(sr, req, filter)
+	sr.Close()
+	for i := len(history) - 1; i >= 0; i-- {
+		err = srv.Send(history[i])
+		if err != nil {
+			listener.Close()
+			return err
+		}
 	}
-	logBroadcaster.NewData(pl)
 
-}
-func (e *echoServer) ReadLog(req *pb.ReadLogRequest, srv pb.ErrorLogger_ReadLogServer) error {
-	fmt.Printf("Listener added for services \"%s\"\n", strings.Join(req.Services, " "))
-	file, err := os.Open(fmt.Sprintf("%s/proto.log", *logdir))
+	// send live
+	err = listener.Handle(srv, func(srv any, data any) error {
+		d := data.(*liveLog)
+		if d.muted && !req.IncludeMuted {
+			return nil
+		}
+		if !filter.Match(d.pl) {
+			return nil
+		}
+		return srv.(pb.ErrorLogger_ReadLogServer).Send(d.pl)
+	})
+	if err == broadcaster.ErrOverflow {
+		// the client may reconnect and replay from the file what it missed
+		return status.Errorf(codes.ResourceExhausted, "too slow to receive new logs, some were dropped")
+	}
 	if err != nil {
 		return err
 	}
-	m := &proto_matcher{req: req}
-	br := streamblock.NewSeekableBlockReader(file)
-	max_to_read := 100
-	// send from log
+	return nil
+}
+
+// read the last matching logs, newest first
+func readHistory(br *streamblock.SegmentedReader, req *pb.ReadLogRequest, filter *logFilter) []*pb.ProtoLog {
+	max_to_read := int(req.LogsToSend)
+	if max_to_read == 0 {
+		max_to_read = default_logs_to_send
+	}
+	if max_to_read > *max_logs_to_send {
+		max_to_read = *max_logs_to_send
+	}
+	m := &proto_matcher{filter: filter}
 	block_counter := 0
-	matching_block_counter := 0
+	var res []*pb.ProtoLog
 	var bys []byte
-	for {
+	var err error
+	for len(res) < max_to_read {
 		if block_counter == 0 {
 			bys, err = br.ReadLastBlock()
 		} else {
 			bys, err = br.ReadPreviousBlock()
 		}
 		block_counter++
+		if streamblock.IsCorruptBlock(err) {
+			fmt.Printf("skipping block in proto.log: %s\n", err)
+			corruptCounter.With(prometheus.Labels{"reason": "block"}).Inc()
+			continue
+		}
 		if err != nil {
 			break
 		}
 		if !m.Match(bys) {
 			continue
 		}
-		matching_block_counter++
-		if matching_block_counter >= max_to_read {
-			break
-		}
-		pl := m.lastProto()
-
-		err = srv.Send(pl)
-		if err != nil {
-			return err
-		}
+		res = append(res, m.lastProto())
 	}
-	fmt.Printf("BlockCounter: %d, MatchingBlockCounter: %d\n", block_counter, matching_block_counter)
-	// send live
-	err = logBroadcaster.Handle(srv, func(srv any, data any) error {
-		d := data.(*pb.ProtoLog)
-		if !match_proto(req, d) {
-			return nil
-		}
-		return srv.(pb.ErrorLogger_ReadLogServer).Send(d)
-	})
-	if err != nil {
-		return err
+	if *debug {
+		fmt.Printf("BlockCounter: %d, MatchingBlockCounter: %d\n", block_counter, len(res))
 	}
-	return nil
-}
-
-type proto_matcher struct {
-	req *pb.ReadLogRequest
-	pl  *pb.ProtoLog
+	return res
 }
 
-func (p *proto_matcher) Match(b []byte) bool {
-	pl := &pb.ProtoLog{}
-	err := utils.UnmarshalBytes(b, pl)
-	if err != nil {
-		return false
+// rotation options for the text logfiles, with the given sync policy
+func textLogOptions(sync string) *filelogger.Options {
+	interval, err := filelogger.ParseInterval(*log_rotate)
+	utils.Bail("invalid -log_rotate", err)
+	sp, err := filelogger.ParseSyncPolicy(sync)
+	utils.Bail("invalid sync policy", err)
+	return &filelogger.Options{
+		MaxMB:       *log_max_mb,
+		Interval:    interval,
+		Generations: *log_generations,
+		MaxAge:      *log_max_age,
+		Compress:    *log_compress,
+		Sync:        sp,
 	}
-	p.pl = pl
-	return match_proto(p.req, p.pl)
-}
-func (p *proto_matcher) lastProto() *pb.ProtoLog {
-	return p.pl
 }
-func match_proto(req *pb.ReadLogRequest, pl *pb.ProtoLog) bool {
-	if req == nil {
-		return true
+
+// framing, index and retention of proto.log
+func protoLogOptions() *streamblock.SegmentOptions {
+	res := &streamblock.SegmentOptions{
+		MaxMB:       *proto_segment_mb,
+		MaxSegments: *proto_segments,
+		MaxAge:      *proto_max_age,
+		Framing:     streamblock.FRAMING_PLAIN,
+		Index:       *use_proto_index,
+		Timestamp:   blockTimestamp,
 	}
-	if len(req.Services) == 0 {
-		return true
+	if *proto_checksums {
+		res.Framing = streamblock.FRAMING_CHECKSUM
 	}
-	if pl.Err == nil {
-		return false
+	sp, err := filelogger.ParseSyncPolicy(*sync_proto)
+	utils.Bail("invalid -sync_proto", err)
+	res.SyncWrites = sp.Mode == filelogger.SYNC_ALWAYS
+	if sp.Mode == filelogger.SYNC_PERIODIC {
+		res.SyncInterval = sp.Interval
 	}
-	svc := strings.ToLower(pl.Err.ServiceName)
-	for _, s := range req.Services {
-		sl := strings.ToLower(s)
-		if strings.Contains(svc, sl) {
-			return true
-		}
-	}
-	return false
+	return res
+}
+
+func protologFilename() string {
+	return fmt.Sprintf("%s/proto.log", *logdir)
 }
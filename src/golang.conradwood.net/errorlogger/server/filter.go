@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/go-easyops/prometheus"
+	"golang.conradwood.net/go-easyops/utils"
+)
+
+// the filter of a ReadLogRequest, prepared for matching many ProtoLogs.
+// all criteria that are set must match. if a criterion has multiple values, any one of them may match
+type logFilter struct {
+	services         []string // lowercase
+	userids          map[string]bool
+	methods          []string // lowercase
+	codes            map[uint32]bool
+	calling_services []string // lowercase
+	requestid        string
+	text             string          // lowercase
+	teams            map[string]bool // lowercase
+}
+
+func newLogFilter(req *pb.ReadLogRequest) *logFilter {
+	res := &logFilter{}
+	if req == nil {
+		return res
+	}
+	res.services = lowercase(req.Services)
+	res.methods = lowercase(req.Methods)
+	res.calling_services = lowercase(req.CallingServices)
+	res.requestid = req.RequestID
+	res.text = strings.ToLower(req.Text)
+	if len(req.UserIDs) != 0 {
+		res.userids = make(map[string]bool)
+		for _, u := range req.UserIDs {
+			res.userids[u] = true
+		}
+	}
+	if len(req.ErrorCodes) != 0 {
+		res.codes = make(map[uint32]bool)
+		for _, c := range req.ErrorCodes {
+			res.codes[c] = true
+		}
+	}
+	if len(req.Teams) != 0 {
+		res.teams = make(map[string]bool)
+		for _, t := range req.Teams {
+			res.teams[strings.ToLower(t)] = true
+		}
+	}
+	return res
+}
+
+// true if the filter has no criteria
+func (f *logFilter) IsEmpty() bool {
+	return len(f.services) == 0 && len(f.userids) == 0 && len(f.methods) == 0 && len(f.codes) == 0 &&
+		len(f.calling_services) == 0 && f.requestid == "" && f.text == "" && len(f.teams) == 0
+}
+
+func (f *logFilter) Match(pl *pb.ProtoLog) bool {
+	if f.IsEmpty() {
+		return true
+	}
+	e := pl.Err
+	if e == nil {
+		return false
+	}
+	if len(f.services) != 0 && !containsAny(strings.ToLower(e.ServiceName), f.services) {
+		return false
+	}
+	if f.userids != nil && !f.userids[e.UserID] {
+		return false
+	}
+	if len(f.methods) != 0 && !containsAny(strings.ToLower(e.MethodName), f.methods) {
+		return false
+	}
+	if f.codes != nil && !f.codes[e.ErrorCode] {
+		return false
+	}
+	if f.requestid != "" && f.requestid != e.RequestID {
+		return false
+	}
+	if f.teams != nil && !f.teams[strings.ToLower(logTeam(pl))] {
+		return false
+	}
+	if len(f.calling_services) != 0 {
+		cs := e.CallingService
+		if cs == nil {
+			return false
+		}
+		email := strings.ToLower(cs.Email)
+		matched := false
+		for _, s := range f.calling_services {
+			if s == strings.ToLower(cs.ID) || (email != "" && strings.Contains(email, s)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if f.text != "" {
+		if !strings.Contains(strings.ToLower(e.LogMessage), f.text) && !strings.Contains(strings.ToLower(e.ErrorMessage), f.text) {
+			return false
+		}
+	}
+	return true
+}
+
+type proto_matcher struct {
+	filter *logFilter
+	pl     *pb.ProtoLog
+}
+
+func (p *proto_matcher) Match(b []byte) bool {
+	pl := &pb.ProtoLog{}
+	err := utils.UnmarshalBytes(b, pl)
+	if err != nil {
+		fmt.Printf("skipping undecodable entry in proto.log: %s\n", err)
+		corruptCounter.With(prometheus.Labels{"reason": "proto"}).Inc()
+		return false
+	}
+	p.pl = pl
+	return p.filter.Match(p.pl)
+}
+func (p *proto_matcher) lastProto() *pb.ProtoLog {
+	return p.pl
+}
+
+// true if s contains any of the substrings
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func lowercase(a []string) []string {
+	var res []string
+	for _, s := range a {
+		res = append(res, strings.ToLower(s))
+	}
+	return res
+}
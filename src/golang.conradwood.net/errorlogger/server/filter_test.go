@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	apb "golang.conradwood.net/apis/auth"
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/errorlogger/ownership"
+)
+
+func TestLogFilter(t *testing.T) {
+	pl := &pb.ProtoLog{Err: &pb.ErrorLogRequest{
+		UserID:         "12",
+		ServiceName:    "foo.FooService",
+		MethodName:     "GetBar",
+		ErrorCode:      13,
+		LogMessage:     "failed to frobnicate",
+		ErrorMessage:   "internal error",
+		RequestID:      "req-1",
+		CallingService: &apb.User{ID: "7", Email: "gateway@services.example.com"},
+	}, Team: "payments"}
+	for _, c := range []struct {
+		req   *pb.ReadLogRequest
+		match bool
+	}{
+		{nil, true},
+		{&pb.ReadLogRequest{}, true},
+		{&pb.ReadLogRequest{Services: []string{"fooservice"}}, true},
+		{&pb.ReadLogRequest{Services: []string{"bar", "foo"}}, true},
+		{&pb.ReadLogRequest{Services: []string{"bar"}}, false},
+		{&pb.ReadLogRequest{UserIDs: []string{"1", "12"}}, true},
+		{&pb.ReadLogRequest{UserIDs: []string{"1"}}, false},
+		{&pb.ReadLogRequest{Methods: []string{"getbar"}}, true},
+		{&pb.ReadLogRequest{Methods: []string{"setbar"}}, false},
+		{&pb.ReadLogRequest{ErrorCodes: []uint32{5, 13}}, true},
+		{&pb.ReadLogRequest{ErrorCodes: []uint32{5}}, false},
+		{&pb.ReadLogRequest{CallingServices: []string{"7"}}, true},
+		{&pb.ReadLogRequest{CallingServices: []string{"gateway"}}, true},
+		{&pb.ReadLogRequest{CallingServices: []string{"8"}}, false},
+		{&pb.ReadLogRequest{RequestID: "req-1"}, true},
+		{&pb.ReadLogRequest{RequestID: "req-2"}, false},
+		{&pb.ReadLogRequest{Text: "FROBNICATE"}, true},
+		{&pb.ReadLogRequest{Text: "internal"}, true},
+		{&pb.ReadLogRequest{Text: "timeout"}, false},
+		{&pb.ReadLogRequest{Teams: []string{"ops", "Payments"}}, true},
+		{&pb.ReadLogRequest{Teams: []string{"ops"}}, false},
+		{&pb.ReadLogRequest{Services: []string{"foo"}, ErrorCodes: []uint32{13}, Text: "frob"}, true},
+		{&pb.ReadLogRequest{Services: []string{"foo"}, ErrorCodes: []uint32{5}, Text: "frob"}, false},
+	} {
+		got := newLogFilter(c.req).Match(pl)
+		if got != c.match {
+			t.Errorf("filter %v: expected match=%v, got %v", c.req, c.match, got)
+		}
+	}
+	if newLogFilter(&pb.ReadLogRequest{Services: []string{"foo"}}).Match(&pb.ProtoLog{}) {
+		t.Errorf("filter matched ProtoLog without error")
+	}
+
+	// logs without a team are matched by the current owner of their service
+	pl.Team = ""
+	f := newLogFilter(&pb.ReadLogRequest{Teams: []string{"foo-team"}})
+	if f.Match(pl) {
+		t.Errorf("filter matched log of a service without owner")
+	}
+	ownershipTable.Set([]*ownership.Owner{{Team: "foo-team", Services: []string{"foo.*"}}})
+	defer ownershipTable.Set(nil)
+	if !f.Match(pl) {
+		t.Errorf("filter did not match log of an owned service")
+	}
+}
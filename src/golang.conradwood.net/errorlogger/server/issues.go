@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/errorlogger/issues"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	max_groups    = flag.Int("max_groups", 10000, "maximum number of error groups to keep")
+	issueTracker  = &issues.Tracker{}
+	save_interval = time.Minute
+)
+
+const (
+	default_groups_to_send = 100 // if ListErrorGroupsRequest does not specify how many
+)
+
+func (e *echoServer) ListErrorGroups(ctx context.Context, req *pb.ListErrorGroupsRequest) (*pb.ErrorGroupList, error) {
+	limit := int(req.Limit)
+	if limit == 0 {
+		limit = default_groups_to_send
+	}
+	return &pb.ErrorGroupList{Groups: issueTracker.List(req, limit)}, nil
+}
+
+func (e *echoServer) SetGroupState(ctx context.Context, req *pb.SetGroupStateRequest) (*pb.ErrorGroup, error) {
+	if req.State == pb.GroupState_REGRESSED {
+		return nil, status.Errorf(codes.InvalidArgument, "groups are only marked as regressed when they occur again after being resolved")
+	}
+	if req.State != pb.GroupState_MUTED && (req.MuteUntil != 0 || req.MuteOccurrences != 0) {
+		return nil, status.Errorf(codes.InvalidArgument, "mute limits are only valid with state MUTED")
+	}
+	eg, err := issueTracker.SetState(req)
+	if err == issues.ErrNotFound {
+		return nil, status.Errorf(codes.NotFound, "no group with fingerprint \"%s\"", req.Fingerprint)
+	}
+	if err == issues.ErrAmbiguous {
+		return nil, status.Errorf(codes.InvalidArgument, "fingerprint \"%s\" matches more than one group", req.Fingerprint)
+	}
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Group %s (%s/%s) is now %s\n", eg.Fingerprint, eg.ServiceName, eg.MethodName, eg.State)
+	// state changes are made by people, do not lose them until the next periodic save
+	saveIssues()
+	return eg, nil
+}
+
+func issuesFilename() string {
+	return fmt.Sprintf("%s/issues.pb", *logdir)
+}
+
+// load the error groups saved by a previous run
+func loadIssues() error {
+	issueTracker.MaxGroups = *max_groups
+	return issueTracker.Load(issuesFilename())
+}
+
+// save the error groups periodically, so that they survive a restart
+func saveIssuesPeriodically() {
+	for {
+		time.Sleep(save_interval)
+		saveIssues()
+	}
+}
+
+func saveIssues() error {
+	err := issueTracker.Save(issuesFilename())
+	if err != nil {
+		fmt.Printf("Failed to save error groups: %s\n", err)
+	}
+	return err
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/errorlogger/dedup"
+	"golang.conradwood.net/errorlogger/queue"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLogBatch(t *testing.T) {
+	var lock sync.Mutex
+	var flushed []*queuedLog
+	logQueue = &queue.Queue{
+		Flush: func(batch []any) {
+			lock.Lock()
+			defer lock.Unlock()
+			for _, b := range batch {
+				flushed = append(flushed, b.(*queuedLog))
+			}
+		},
+	}
+	e := &echoServer{}
+	req := &pb.LogBatchRequest{}
+	for _, m := range []string{"a", "b", "c"} {
+		req.Logs = append(req.Logs, &pb.ErrorLogRequest{ServiceName: "svc", MethodName: m, ErrorCode: 2})
+	}
+	res, err := e.LogBatch(context.Background(), req)
+	if err != nil {
+		t.Fatalf("LogBatch failed: %s", err)
+	}
+	if len(res.Acks) != 3 {
+		t.Fatalf("expected 3 acks, got %d", len(res.Acks))
+	}
+	for i, a := range res.Acks {
+		if a.Index != uint32(i) || !a.Accepted {
+			t.Errorf("ack %d: %v", i, a)
+		}
+	}
+	logQueue.Close()
+	if len(flushed) != 3 || flushed[0].req.MethodName != "a" || flushed[2].req.MethodName != "c" {
+		t.Errorf("logs not queued in order")
+	}
+
+	// entries which cannot be queued are not acknowledged
+	res, err = e.LogBatch(context.Background(), req)
+	if err != nil {
+		t.Fatalf("LogBatch failed: %s", err)
+	}
+	for i, a := range res.Acks {
+		if a.Accepted || a.Error == "" {
+			t.Errorf("ack %d: expected entry to be rejected, got %v", i, a)
+		}
+	}
+}
+
+func TestLogDuplicates(t *testing.T) {
+	recentKeys = &dedup.Set{}
+	var flushed []*queuedLog
+	logQueue = &queue.Queue{
+		Flush: func(batch []any) {
+			for _, b := range batch {
+				flushed = append(flushed, b.(*queuedLog))
+			}
+		},
+	}
+	e := &echoServer{}
+	for _, key := range []string{"k1", "", "k1", "k2", "", "k2"} {
+		_, err := e.Log(context.Background(), &pb.ErrorLogRequest{ServiceName: "svc", IdempotencyKey: key})
+		if err != nil {
+			t.Fatalf("Log failed: %s", err)
+		}
+	}
+	logQueue.Close()
+	if len(flushed) != 4 {
+		t.Errorf("expected 4 logs (2 with keys, 2 without), got %d", len(flushed))
+	}
+
+	// a log which could not be queued is not a duplicate when it is retried
+	e.Log(context.Background(), &pb.ErrorLogRequest{ServiceName: "svc", IdempotencyKey: "k3"})
+	if recentKeys.Seen("k3") {
+		t.Errorf("key of a rejected log was remembered")
+	}
+
+	// nor is a log which was accepted, but then dropped from the queue
+	flushed = nil
+	started := make(chan bool)
+	release := make(chan bool)
+	logQueue = &queue.Queue{
+		Size:   2,
+		Policy: queue.POLICY_DROP_OLDEST,
+		OnDrop: droppedLog,
+		Flush: func(batch []any) {
+			started <- true
+			<-release
+			for _, b := range batch {
+				flushed = append(flushed, b.(*queuedLog))
+			}
+		},
+	}
+	e.Log(context.Background(), &pb.ErrorLogRequest{ServiceName: "svc", IdempotencyKey: "k4"})
+	<-started // the worker blocks with k4, so the next logs stay queued
+	for _, key := range []string{"k5", "k6", "k7", "k5"} {
+		_, err := e.Log(context.Background(), &pb.ErrorLogRequest{ServiceName: "svc", IdempotencyKey: key})
+		if err != nil {
+			t.Fatalf("Log failed: %s", err)
+		}
+	}
+	go func() {
+		for range started {
+		}
+	}()
+	close(release)
+	logQueue.Close()
+	close(started)
+	var keys []string
+	for _, f := range flushed {
+		keys = append(keys, f.req.IdempotencyKey)
+	}
+	if strings.Join(keys, " ") != "k4 k7 k5" {
+		t.Errorf("expected the retry of the dropped k5 to be logged, got %v", keys)
+	}
+}
+
+func TestLogDropped(t *testing.T) {
+	recentKeys = &dedup.Set{}
+	started := make(chan bool, 10)
+	release := make(chan bool)
+	logQueue = &queue.Queue{
+		Size:   1,
+		Policy: queue.POLICY_DROP_NEWEST,
+		OnDrop: droppedLog,
+		Flush: func(batch []any) {
+			started <- true
+			<-release
+		},
+	}
+	e := &echoServer{}
+	e.Log(context.Background(), &pb.ErrorLogRequest{ServiceName: "svc"})
+	<-started
+	e.Log(context.Background(), &pb.ErrorLogRequest{ServiceName: "svc"})
+	_, err := e.Log(context.Background(), &pb.ErrorLogRequest{ServiceName: "svc", IdempotencyKey: "k1"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected ResourceExhausted for a dropped log, got %v", err)
+	}
+	if recentKeys.Seen("k1") {
+		t.Errorf("key of a dropped log was remembered")
+	}
+	close(release)
+	logQueue.Close()
+}
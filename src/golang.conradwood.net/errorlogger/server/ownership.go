@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/errorlogger/ownership"
+)
+
+var (
+	ownership_file = flag.String("ownership", "", "yaml `file` mapping service name patterns to teams, contacts and escalation channels. it is reloaded when it changes")
+	ownershipTable = &ownership.Table{}
+	ownershipFile  = &configFile{filename: ownership_file, what: "ownership table", load: loadOwnership}
+)
+
+func loadOwnership(filename string) error {
+	owners, err := ownership.Load(filename)
+	if err != nil {
+		return err
+	}
+	ownershipTable.Set(owners)
+	fmt.Printf("Loaded %d teams from %s\n", len(owners), filename)
+	return nil
+}
+
+// the team of a log. logs written before the service had an owner (or before teams were recorded) get the current one
+func logTeam(pl *pb.ProtoLog) string {
+	if pl.Team != "" || pl.Err == nil {
+		return pl.Team
+	}
+	return ownershipTable.Team(pl.Err.ServiceName)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/errorlogger/filelogger"
+	"golang.conradwood.net/go-easyops/auth"
+	"golang.conradwood.net/go-easyops/authremote"
+	"golang.conradwood.net/go-easyops/utils"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	last_received uint32 // only used by the flush worker
+)
+
+// a log accepted by Log(), waiting to be written
+type queuedLog struct {
+	req      *pb.ErrorLogRequest
+	received uint32
+}
+
+// a log sent to the ReadLog() listeners
+type liveLog struct {
+	pl    *pb.ProtoLog
+	muted bool // its group is muted
+}
+
+// write a batch of queued logs to proto.log and the text logfiles. each logfile is written to once per batch
+func flushLogs(batch []any) {
+	ctx := authremote.Context()
+	var all, small, users_buf bytes.Buffer
+	var per_user []string // filenames, in the order of the batch
+	per_user_buf := make(map[string]*bytes.Buffer)
+	var pls []*pb.ProtoLog
+	var muted []bool
+	// the emails are left out if the lookups are too slow. they share one timeout for the whole batch
+	var userids []string
+	for _, b := range batch {
+		if id := b.(*queuedLog).req.UserID; id != "" {
+			userids = append(userids, id)
+		}
+	}
+	users := userCache.GetAll(userids)
+	for _, b := range batch {
+		ql := b.(*queuedLog)
+		req := ql.req
+		// concurrent Log() calls may queue in a different order than they took the timestamp. proto.log is kept in chronological order.
+		// pl is shared with the issue tracker and alerts from here on, so it is not modified after this
+		received := ql.received
+		if received < last_received {
+			received = last_received
+		}
+		last_received = received
+		pl := &pb.ProtoLog{
+			Err:      req,
+			User:     auth.GetUser(ctx),
+			Service:  auth.GetService(ctx),
+			Received: received,
+			Team:     ownershipTable.Team(req.ServiceName),
+		}
+		pls = append(pls, pl)
+		added := issueTracker.Add(pl)
+		muted = append(muted, added.Muted)
+		if !added.Muted {
+			// muted groups do not raise alerts either
+			alertEngine.Process(pl, added.Fingerprint, added.New)
+		}
+		if added.New {
+			notifyNewGroup(pl, added.Fingerprint)
+		}
+		anomalyDetector.Add(pl)
+		user := users[req.UserID]
+		email := ""
+		if user != nil {
+			email = user.Email
+		}
+		s := formatLog(req, email)
+		all.WriteString(s)
+		if req.UserID != "" {
+			users_buf.WriteString(s)
+		}
+		if user != nil {
+			fname := userLogFilename(user)
+			buf := per_user_buf[fname]
+			if buf == nil {
+				buf = &bytes.Buffer{}
+				per_user_buf[fname] = buf
+				per_user = append(per_user, fname)
+			}
+			buf.WriteString(s)
+		}
+		ec := codes.Code(req.ErrorCode)
+		if !added.Muted && ec != codes.NotFound && ec != codes.PermissionDenied && ec != codes.Unauthenticated {
+			small.WriteString(s)
+		}
+	}
+	storeprotologs(pls, muted)
+	writeBuffer(logger, &all)
+	writeBuffer(userlog, &users_buf)
+	for _, fname := range per_user {
+		_, err := userLogs.Write(fname, per_user_buf[fname].Bytes())
+		if err != nil {
+			fmt.Printf("Failed to write to %s: %s\n", fname, err)
+		}
+	}
+	writeBuffer(smallLogger, &small)
+	queueDepth.Set(float64(logQueue.Len()))
+}
+
+func writeBuffer(fl *filelogger.FileLogger, buf *bytes.Buffer) {
+	if buf.Len() == 0 {
+		return
+	}
+	fl.Write(buf.Bytes())
+}
+
+// the line for the text logfiles
+func formatLog(req *pb.ErrorLogRequest, email string) string {
+	svcinfo := "unavailable"
+	if req.CallingService != nil {
+		svcinfo = fmt.Sprintf("%s(%s)", req.CallingService.ID, req.CallingService.Email)
+	}
+	return fmt.Sprintf("%s: #%05s(%s) [%s->%s.%s] %s %s\n",
+		utils.TimestampString(req.Timestamp),
+		req.UserID, email,
+		svcinfo,
+		req.ServiceName, req.MethodName,
+		(codes.Code(req.ErrorCode)).String(),
+		req.LogMessage,
+	)
+}
+
+// write to proto.log, with one fsync per batch, and broadcast what was written. muted[i] is true if the group of pls[i] is muted
+func storeprotologs(pls []*pb.ProtoLog, muted []bool) {
+	protolock.Lock()
+	defer protolock.Unlock()
+	var written []*liveLog
+	var blocks [][]byte
+	for i, pl := range pls {
+		bs, err := utils.MarshalBytes(pl)
+		if err != nil {
+			fmt.Printf("Failed to marshal error proto: %s\n", err)
+			continue
+		}
+		written = append(written, &liveLog{pl: pl, muted: muted[i]})
+		blocks = append(blocks, bs)
+	}
+	n, err := protolog.WriteBlocks(blocks)
+	if err != nil {
+		fmt.Printf("failed to write proto: %s\n", err)
+	}
+	for _, ll := range written[:n] {
+		logBroadcaster.NewData(ll)
+	}
+}
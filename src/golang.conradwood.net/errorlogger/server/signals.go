@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"golang.conradwood.net/errorlogger/filelogger"
+)
+
+// reopen all logfiles on SIGHUP, so that an external logrotate can move them. config files are reloaded, too
+func reopenOnHangup() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	for range c {
+		fmt.Printf("Received SIGHUP, reopening logfiles\n")
+		err := filelogger.ReopenAll()
+		if err != nil {
+			fmt.Printf("Failed to reopen logfiles: %s\n", err)
+		}
+		err = protolog.Reopen()
+		if err != nil {
+			fmt.Printf("Failed to reopen protologfile: %s\n", err)
+		}
+		reloadConfigFiles()
+	}
+}
+
+// on SIGTERM or SIGINT, stop accepting logs, write all queued logs and exit. exits with status 1 if anything could not be saved
+func drainOnTerminate() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGTERM, syscall.SIGINT)
+	sig := <-c
+	fmt.Printf("Received %s, writing %d queued logs\n", sig, logQueue.Len())
+	err := drain()
+	if err != nil {
+		fmt.Printf("Failed to shut down cleanly: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Queue drained, exiting\n")
+	os.Exit(0)
+}
+
+// write all queued logs, save state and close the logfiles. carries on after errors and returns all of them
+func drain() error {
+	logQueue.Close()
+	userLogs.Close()
+	notifier.Close()
+	var errs []error
+	errs = append(errs, saveIssues(), saveBaselines())
+	for _, fl := range []*filelogger.FileLogger{logger, smallLogger, userlog} {
+		errs = append(errs, fl.Close())
+	}
+	err := protolog.Close()
+	if err != nil {
+		fmt.Printf("Failed to close protologfile: %s\n", err)
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"time"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/errorlogger/stats"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	max_stats_window = flag.Duration("max_stats_window", 7*24*time.Hour, "maximum time window of a GetStats() request. it reads proto.log for the whole window")
+)
+
+const (
+	default_stats_window = time.Hour // if StatsRequest does not specify a start
+)
+
+func (e *echoServer) GetStats(ctx context.Context, req *pb.StatsRequest) (*pb.StatsResponse, error) {
+	end := req.EndTimestamp
+	if end == 0 {
+		end = uint32(time.Now().Unix())
+	}
+	start := req.StartTimestamp
+	if start == 0 {
+		start = end - uint32(default_stats_window.Seconds())
+	}
+	if start > end {
+		return nil, status.Errorf(codes.InvalidArgument, "start (%d) is after end (%d)", start, end)
+	}
+	if time.Duration(end-start)*time.Second > *max_stats_window {
+		return nil, status.Errorf(codes.InvalidArgument, "time window of %v is larger than %v", time.Duration(end-start)*time.Second, *max_stats_window)
+	}
+	agg := stats.NewAggregator(req.GroupBy)
+	err := readTimeRange(start, end, func(pl *pb.ProtoLog) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		pl.Team = logTeam(pl)
+		agg.Add(pl)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pb.StatsResponse{
+		StartTimestamp: start,
+		EndTimestamp:   end,
+		Total:          agg.Total(),
+		Entries:        agg.Entries(int(req.TopN)),
+	}, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/errorlogger/streamblock"
+	"golang.conradwood.net/go-easyops/prometheus"
+	"golang.conradwood.net/go-easyops/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// send all matching logs received between StartTimestamp and EndTimestamp, oldest first
+func sendTimeRange(req *pb.ReadLogRequest, filter *logFilter, srv pb.ErrorLogger_ReadLogServer) error {
+	if req.StartTimestamp > req.EndTimestamp {
+		return status.Errorf(codes.InvalidArgument, "start (%d) is after end (%d)", req.StartTimestamp, req.EndTimestamp)
+	}
+	return readTimeRange(req.StartTimestamp, req.EndTimestamp, func(pl *pb.ProtoLog) error {
+		if !filter.Match(pl) {
+			return nil
+		}
+		return srv.Send(pl)
+	})
+}
+
+// call f for all logs in proto.log received between start and end (inclusive), oldest first. stops at the first error f returns
+func readTimeRange(start, end uint32, f func(pl *pb.ProtoLog) error) error {
+	br, err := protolog.OpenReader()
+	if err != nil {
+		return err
+	}
+	defer br.Close()
+	err = br.SeekToTime(start, blockTimestamp)
+	if err != nil {
+		return err
+	}
+	for {
+		bys, err := br.ReadBlock()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// the end, or a log which is being written right now
+			return nil
+		}
+		if streamblock.IsCorruptBlock(err) {
+			fmt.Printf("skipping block in proto.log: %s\n", err)
+			corruptCounter.With(prometheus.Labels{"reason": "block"}).Inc()
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		pl := &pb.ProtoLog{}
+		err = utils.UnmarshalBytes(bys, pl)
+		if err != nil {
+			fmt.Printf("skipping undecodable entry in proto.log: %s\n", err)
+			corruptCounter.With(prometheus.Labels{"reason": "proto"}).Inc()
+			continue
+		}
+		ts := receivedTimestamp(pl)
+		if ts > end && pl.Received != 0 {
+			// received timestamps are in order, client timestamps of older logs may not be
+			return nil
+		}
+		if ts > end {
+			continue
+		}
+		if ts < start {
+			continue
+		}
+		err = f(pl)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// the timestamp of a block in proto.log
+func blockTimestamp(b []byte) (uint32, error) {
+	pl := &pb.ProtoLog{}
+	err := utils.UnmarshalBytes(b, pl)
+	if err != nil {
+		return 0, err
+	}
+	return receivedTimestamp(pl), nil
+}
+
+// when the log was received. older logs do not have this, so fall back to the timestamp the client reported
+func receivedTimestamp(pl *pb.ProtoLog) uint32 {
+	if pl.Received != 0 {
+		return pl.Received
+	}
+	if pl.Err != nil {
+		return pl.Err.Timestamp
+	}
+	return 0
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/errorlogger/streamblock"
+	"golang.conradwood.net/go-easyops/utils"
+)
+
+func TestReadTimeRange(t *testing.T) {
+	var err error
+	protolog, err = streamblock.OpenSegmentedWriter(filepath.Join(t.TempDir(), "proto.log"), &streamblock.SegmentOptions{})
+	if err != nil {
+		t.Fatalf("failed to open: %s", err)
+	}
+	defer protolog.Close()
+	// logs written before the received timestamp existed, one with a skewed client clock, then newer ones
+	for _, pl := range []*pb.ProtoLog{
+		{Err: &pb.ErrorLogRequest{Timestamp: 100, LogMessage: "a"}},
+		{Err: &pb.ErrorLogRequest{Timestamp: 500, LogMessage: "skewed"}},
+		{Err: &pb.ErrorLogRequest{Timestamp: 150, LogMessage: "b"}},
+		{Err: &pb.ErrorLogRequest{Timestamp: 900, LogMessage: "c"}, Received: 200},
+		{Err: &pb.ErrorLogRequest{Timestamp: 100, LogMessage: "d"}, Received: 300},
+	} {
+		bs, err := utils.MarshalBytes(pl)
+		if err != nil {
+			t.Fatalf("failed to marshal: %s", err)
+		}
+		_, err = protolog.Write(bs)
+		if err != nil {
+			t.Fatalf("failed to write: %s", err)
+		}
+	}
+	var got string
+	err = readTimeRange(100, 250, func(pl *pb.ProtoLog) error {
+		got = got + pl.Err.LogMessage
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read: %s", err)
+	}
+	if got != "abc" {
+		t.Errorf("expected logs a, b and c, got \"%s\"", got)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+	"golang.conradwood.net/errorlogger/webhook"
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	webhook_urls = flag.String("webhooks", "", "comma delimited list of URLs to POST alerts and new error groups to (as JSON)")
+	webhook_rate = flag.Float64("webhook_rate", 60, "maximum notifications per minute to each webhook")
+	notifier     = &webhook.Notifier{}
+)
+
+// deliver notifications left over from the previous run and start delivering new ones
+func startWebhooks() error {
+	notifier.URLs = splitList(*webhook_urls)
+	notifier.RatePerMinute = *webhook_rate
+	notifier.Outbox = fmt.Sprintf("%s/webhooks", *logdir)
+	return notifier.Start()
+}
+
+func notifyAlert(a *pb.Alert) {
+	p := newPayload("alert", a.Log, a.Fingerprint, a.Count)
+	p.Rule = a.Rule
+	p.Description = a.Description
+	notify(p)
+}
+
+func notifyAnomaly(a *pb.Anomaly) {
+	p := &webhook.Payload{
+		Event:       "anomaly",
+		Description: fmt.Sprintf("%d errors in %ds, baseline %.1f±%.1f", a.Count, a.IntervalSeconds, a.Baseline, a.Deviation),
+		Service:     a.ServiceName,
+		Method:      a.MethodName,
+		Count:       a.Count,
+		Timestamp:   a.Started,
+	}
+	addOwner(p, a.ServiceName)
+	notify(p)
+}
+
+// the first error of a group was received
+func notifyNewGroup(pl *pb.ProtoLog, fingerprint string) {
+	notify(newPayload("new_group", pl, fingerprint, 1))
+}
+
+func notify(p *webhook.Payload) {
+	if len(notifier.URLs) == 0 {
+		return
+	}
+	err := notifier.Send(p)
+	if err != nil {
+		fmt.Printf("Failed to send %s notification: %s\n", p.Event, err)
+	}
+}
+
+func newPayload(event string, pl *pb.ProtoLog, fingerprint string, count uint64) *webhook.Payload {
+	p := &webhook.Payload{Event: event, Fingerprint: fingerprint, Count: count}
+	if pl == nil || pl.Err == nil {
+		return p
+	}
+	e := pl.Err
+	p.Service = e.ServiceName
+	p.Method = e.MethodName
+	p.Code = e.ErrorCode
+	p.CodeName = codes.Code(e.ErrorCode).String()
+	p.Message = e.LogMessage
+	p.RequestID = e.RequestID
+	p.Timestamp = receivedTimestamp(pl)
+	p.Team = logTeam(pl)
+	addOwner(p, e.ServiceName)
+	return p
+}
+
+// the contact and escalation channel of the service
+func addOwner(p *webhook.Payload, service string) {
+	o := ownershipTable.Lookup(service)
+	if o == nil {
+		return
+	}
+	if p.Team == "" {
+		p.Team = o.Team
+	}
+	p.Contact = o.Contact
+	p.Escalation = o.Escalation
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, x := range strings.Split(s, ",") {
+		x = strings.TrimSpace(x)
+		if x != "" {
+			res = append(res, x)
+		}
+	}
+	return res
+}
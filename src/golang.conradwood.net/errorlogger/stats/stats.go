@@ -0,0 +1,114 @@
+/*
+counts errors per combination of fields, e.g. per service and method
+*/
+package stats
+
+import (
+	"sort"
+
+	pb "golang.conradwood.net/apis/errorlogger"
+)
+
+type Aggregator struct {
+	groupBy map[pb.StatsField]bool
+	counts  map[key]uint64
+	total   uint64
+}
+
+// the fields an error is counted by. fields which are not grouped by are left empty
+type key struct {
+	service string
+	method  string
+	code    uint32
+	user    string
+	caller  string
+	team    string
+}
+
+// an aggregator counting per combination of the given fields
+func NewAggregator(groupBy []pb.StatsField) *Aggregator {
+	a := &Aggregator{groupBy: make(map[pb.StatsField]bool), counts: make(map[key]uint64)}
+	for _, f := range groupBy {
+		a.groupBy[f] = true
+	}
+	return a
+}
+
+// count an error
+func (a *Aggregator) Add(pl *pb.ProtoLog) {
+	if pl.Err == nil {
+		return
+	}
+	a.total++
+	if len(a.groupBy) == 0 {
+		return
+	}
+	req := pl.Err
+	var k key
+	if a.groupBy[pb.StatsField_SERVICE] {
+		k.service = req.ServiceName
+	}
+	if a.groupBy[pb.StatsField_METHOD] {
+		k.method = req.MethodName
+	}
+	if a.groupBy[pb.StatsField_CODE] {
+		k.code = req.ErrorCode
+	}
+	if a.groupBy[pb.StatsField_USER] {
+		k.user = req.UserID
+	}
+	if a.groupBy[pb.StatsField_CALLING_SERVICE] && req.CallingService != nil {
+		k.caller = req.CallingService.ID
+	}
+	if a.groupBy[pb.StatsField_TEAM] {
+		k.team = pl.Team
+	}
+	a.counts[k]++
+}
+
+// the number of errors added
+func (a *Aggregator) Total() uint64 {
+	return a.total
+}
+
+// the counts, largest first. at most topN are returned, 0 returns all
+func (a *Aggregator) Entries(topN int) []*pb.StatsEntry {
+	var res []*pb.StatsEntry
+	for k, c := range a.counts {
+		res = append(res, &pb.StatsEntry{
+			ServiceName:    k.service,
+			MethodName:     k.method,
+			ErrorCode:      k.code,
+			UserID:         k.user,
+			CallingService: k.caller,
+			Team:           k.team,
+			Count:          c,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.ServiceName != b.ServiceName {
+			return a.ServiceName < b.ServiceName
+		}
+		if a.MethodName != b.MethodName {
+			return a.MethodName < b.MethodName
+		}
+		if a.ErrorCode != b.ErrorCode {
+			return a.ErrorCode < b.ErrorCode
+		}
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+		if a.CallingService != b.CallingService {
+			return a.CallingService < b.CallingService
+		}
+		return a.Team < b.Team
+	})
+	if topN > 0 && len(res) > topN {
+		res = res[:topN]
+	}
+	return res
+}
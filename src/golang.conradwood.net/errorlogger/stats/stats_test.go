@@ -0,0 +1,82 @@
+package stats
+
+import (
+	"testing"
+
+	apb "golang.conradwood.net/apis/auth"
+	pb "golang.conradwood.net/apis/errorlogger"
+)
+
+var test_teams = map[string]string{"foo.Foo": "foo-team", "bar.Bar": "bar-team"}
+
+func newLog(service, method string, code uint32, user, caller string) *pb.ProtoLog {
+	return &pb.ProtoLog{
+		Err: &pb.ErrorLogRequest{
+			ServiceName:    service,
+			MethodName:     method,
+			ErrorCode:      code,
+			UserID:         user,
+			CallingService: &apb.User{ID: caller},
+		},
+		Team: test_teams[service],
+	}
+}
+
+func TestAggregator(t *testing.T) {
+	logs := []*pb.ProtoLog{
+		newLog("foo.Foo", "Get", 5, "1", "10"),
+		newLog("foo.Foo", "Get", 5, "2", "10"),
+		newLog("foo.Foo", "Get", 13, "1", "11"),
+		newLog("foo.Foo", "Put", 13, "1", "11"),
+		newLog("bar.Bar", "Get", 13, "3", "10"),
+		{}, // not counted
+	}
+	for _, tc := range []struct {
+		groupBy  []pb.StatsField
+		topN     int
+		expected []*pb.StatsEntry
+	}{
+		{nil, 0, nil},
+		{[]pb.StatsField{pb.StatsField_SERVICE, pb.StatsField_METHOD}, 2, []*pb.StatsEntry{
+			{ServiceName: "foo.Foo", MethodName: "Get", Count: 3},
+			{ServiceName: "bar.Bar", MethodName: "Get", Count: 1},
+		}},
+		{[]pb.StatsField{pb.StatsField_METHOD}, 0, []*pb.StatsEntry{
+			{MethodName: "Get", Count: 4},
+			{MethodName: "Put", Count: 1},
+		}},
+		{[]pb.StatsField{pb.StatsField_CODE, pb.StatsField_USER}, 0, []*pb.StatsEntry{
+			{ErrorCode: 13, UserID: "1", Count: 2},
+			{ErrorCode: 5, UserID: "1", Count: 1},
+			{ErrorCode: 5, UserID: "2", Count: 1},
+			{ErrorCode: 13, UserID: "3", Count: 1},
+		}},
+		{[]pb.StatsField{pb.StatsField_TEAM}, 0, []*pb.StatsEntry{
+			{Team: "foo-team", Count: 4},
+			{Team: "bar-team", Count: 1},
+		}},
+		{[]pb.StatsField{pb.StatsField_CALLING_SERVICE}, 1, []*pb.StatsEntry{
+			{CallingService: "10", Count: 3},
+		}},
+	} {
+		a := NewAggregator(tc.groupBy)
+		for _, pl := range logs {
+			a.Add(pl)
+		}
+		if a.Total() != 5 {
+			t.Errorf("%v: expected total 5, got %d", tc.groupBy, a.Total())
+		}
+		got := a.Entries(tc.topN)
+		if len(got) != len(tc.expected) {
+			t.Errorf("%v: expected %v, got %v", tc.groupBy, tc.expected, got)
+			continue
+		}
+		for i, e := range tc.expected {
+			g := got[i]
+			if g.ServiceName != e.ServiceName || g.MethodName != e.MethodName || g.ErrorCode != e.ErrorCode ||
+				g.UserID != e.UserID || g.CallingService != e.CallingService || g.Team != e.Team || g.Count != e.Count {
+				t.Errorf("%v: entry %d: expected %v, got %v", tc.groupBy, i, e, g)
+			}
+		}
+	}
+}
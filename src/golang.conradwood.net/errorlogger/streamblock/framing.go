@@ -0,0 +1,68 @@
+package streamblock
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// how blocks are framed on disk. Readers detect the framing of each block, so files may contain a mix
+type Framing int
+
+const (
+	FRAMING_PLAIN    Framing = 1 // escaped data between START_BYTE and END_BYTE
+	FRAMING_CHECKSUM Framing = 2 // like FRAMING_PLAIN, but the data is prefixed with a CHECKSUM_MARKER and a crc32 of the data
+)
+
+var (
+	crc_table = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// returned by readers for blocks which are damaged, e.g. by a torn write or a bit flip
+type CorruptBlockError struct {
+	Offset int64 // offset of the START_BYTE of the block (or where it should have been)
+	Reason string
+}
+
+func (c *CorruptBlockError) Error() string {
+	return fmt.Sprintf("corrupt block at offset %d: %s", c.Offset, c.Reason)
+}
+
+// true if err is (or wraps) a *CorruptBlockError. Readers can skip past such blocks
+func IsCorruptBlock(err error) bool {
+	var cbe *CorruptBlockError
+	return errors.As(err, &cbe)
+}
+
+// prefix the block with its checksum
+func checksum_block(block []byte) []byte {
+	res := make([]byte, 4, len(block)+4)
+	binary.BigEndian.PutUint32(res, crc32.Checksum(block, crc_table))
+	return append(res, block...)
+}
+
+// given data as read from the block starting at offset, unescape it and verify its checksum (if any)
+func decode_block(read []byte, offset int64) ([]byte, error) {
+	checksummed := false
+	if len(read) >= 2 && read[0] == ESCAPE_BYTE && read[1] == CHECKSUM_MARKER {
+		checksummed = true
+		read = read[2:]
+	}
+	b, err := unescape_block(read)
+	if err != nil {
+		return nil, &CorruptBlockError{Offset: offset, Reason: err.Error()}
+	}
+	if !checksummed {
+		return b, nil
+	}
+	if len(b) < 4 {
+		return nil, &CorruptBlockError{Offset: offset, Reason: "block too short for checksum"}
+	}
+	sum := binary.BigEndian.Uint32(b)
+	b = b[4:]
+	if crc32.Checksum(b, crc_table) != sum {
+		return nil, &CorruptBlockError{Offset: offset, Reason: "checksum mismatch"}
+	}
+	return b, nil
+}
@@ -0,0 +1,268 @@
+package streamblock
+
+/*
+an optional index for a file of blocks. It is stored in a sidecar file (see IndexFilename) and maps
+block numbers and timestamps to byte offsets, so that readers do not need to scan the block stream.
+
+The index file consists of a header followed by one fixed-size record per block, in the order the
+blocks are stored in the data file. The index is rebuilt from the data file if it is missing or does
+not match the data file.
+*/
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+const (
+	index_record_size = 16
+)
+
+var (
+	index_header = []byte{'S', 'B', 'I', 'D', 'X', 0, 0, 1} // magic and version
+)
+
+// the location and timestamp of a block in a block stream
+type IndexEntry struct {
+	Offset    int64  // offset of the START_BYTE
+	Length    uint32 // including START_BYTE and END_BYTE
+	Timestamp uint32
+}
+
+// end of the block (exclusive)
+func (ie IndexEntry) End() int64 {
+	return ie.Offset + int64(ie.Length)
+}
+
+type Index struct {
+	lock     sync.Mutex
+	filename string
+	file     *os.File
+	tsf      TimestampFunc
+	entries  []IndexEntry
+	size     int64 // size of the data file
+}
+
+// the name of the index file for a data file
+func IndexFilename(datafile string) string {
+	return datafile + ".idx"
+}
+
+// open (and if necessary create or update) the index for datafile. tsf is used to timestamp blocks which are not indexed yet
+func OpenIndex(datafile string, tsf TimestampFunc) (*Index, error) {
+	idx := &Index{filename: IndexFilename(datafile), tsf: tsf}
+	data, err := os.Open(datafile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		// no data yet, start from an empty index
+		err = idx.rewrite()
+		if err != nil {
+			return nil, err
+		}
+		return idx, nil
+	}
+	defer data.Close()
+	st, err := data.Stat()
+	if err != nil {
+		return nil, err
+	}
+	idx.size = st.Size()
+
+	entries, err := readIndexFile(idx.filename)
+	if err == nil && !entriesMatch(data, entries, idx.size) {
+		fmt.Printf("[streamblock] index %s is stale, rebuilding\n", idx.filename)
+		entries = nil
+	} else if err != nil && !os.IsNotExist(err) {
+		fmt.Printf("[streamblock] index %s is invalid (%s), rebuilding\n", idx.filename, err)
+	}
+	idx.entries = entries
+
+	// index blocks written after the last indexed one
+	offset := int64(0)
+	if len(entries) > 0 {
+		offset = entries[len(entries)-1].End()
+	}
+	br := NewSeekableBlockReader(data)
+	err = br.seekTo(offset)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		b, err := br.ReadBlock()
+		if IsCorruptBlock(err) {
+			// not indexed, so indexed readers skip it
+			continue
+		}
+		if err != nil {
+			// io.EOF or a trailing partial block
+			break
+		}
+		idx.entries = append(idx.entries, idx.newEntry(br.block_start, br.pos-br.block_start, b))
+	}
+
+	err = idx.rewrite()
+	if err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+func (idx *Index) newEntry(offset, length int64, block []byte) IndexEntry {
+	res := IndexEntry{Offset: offset, Length: uint32(length)}
+	if idx.tsf != nil {
+		ts, err := idx.tsf(block)
+		if err == nil {
+			res.Timestamp = ts
+		}
+	}
+	if len(idx.entries) > 0 {
+		// keep the index sorted by time even if a block could not be timestamped
+		prev := idx.entries[len(idx.entries)-1].Timestamp
+		if res.Timestamp < prev {
+			res.Timestamp = prev
+		}
+	}
+	return res
+}
+
+// add a block, which was written to the data file at the current end, to the index
+func (idx *Index) add(length int, block []byte) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	e := idx.newEntry(idx.size, int64(length), block)
+	idx.size = e.End()
+	idx.entries = append(idx.entries, e)
+	if idx.file == nil {
+		return fmt.Errorf("index %s is closed", idx.filename)
+	}
+	_, err := idx.file.Write(encodeIndexEntry(e))
+	return err
+}
+
+// n bytes were appended to the data file which are not a complete block (e.g. a write failed part way through)
+func (idx *Index) skip(n int) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	idx.size += int64(n)
+}
+
+// a snapshot of the index entries. The returned slice must not be modified
+func (idx *Index) Entries() []IndexEntry {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	return idx.entries[:len(idx.entries):len(idx.entries)]
+}
+
+func (idx *Index) Close() error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+	if idx.file == nil {
+		return nil
+	}
+	err := idx.file.Close()
+	idx.file = nil
+	return err
+}
+
+// write the complete index to disk and keep it open for appending
+func (idx *Index) rewrite() error {
+	tmpname := idx.filename + ".tmp"
+	f, err := os.Create(tmpname)
+	if err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	buf.Write(index_header)
+	for _, e := range idx.entries {
+		buf.Write(encodeIndexEntry(e))
+	}
+	_, err = f.Write(buf.Bytes())
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpname, idx.filename)
+	if err != nil {
+		return err
+	}
+	idx.file, err = os.OpenFile(idx.filename, os.O_WRONLY|os.O_APPEND, 0666)
+	return err
+}
+
+func encodeIndexEntry(e IndexEntry) []byte {
+	res := make([]byte, index_record_size)
+	binary.LittleEndian.PutUint64(res, uint64(e.Offset))
+	binary.LittleEndian.PutUint32(res[8:], e.Length)
+	binary.LittleEndian.PutUint32(res[12:], e.Timestamp)
+	return res
+}
+
+// read an index file. a partially written record at the end is ignored
+func readIndexFile(filename string) ([]IndexEntry, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < len(index_header) || !bytes.Equal(b[:len(index_header)], index_header) {
+		return nil, fmt.Errorf("invalid header")
+	}
+	b = b[len(index_header):]
+	var res []IndexEntry
+	for len(b) >= index_record_size {
+		e := IndexEntry{
+			Offset:    int64(binary.LittleEndian.Uint64(b)),
+			Length:    binary.LittleEndian.Uint32(b[8:]),
+			Timestamp: binary.LittleEndian.Uint32(b[12:]),
+		}
+		res = append(res, e)
+		b = b[index_record_size:]
+	}
+	return res, nil
+}
+
+// check if the first and last entries point to blocks in the data file
+func entriesMatch(data io.ReaderAt, entries []IndexEntry, size int64) bool {
+	if len(entries) == 0 {
+		return true
+	}
+	for _, e := range []IndexEntry{entries[0], entries[len(entries)-1]} {
+		if e.Length < 2 || e.End() > size {
+			return false
+		}
+		b := make([]byte, 1)
+		_, err := data.ReadAt(b, e.Offset)
+		if err != nil || b[0] != START_BYTE {
+			return false
+		}
+		_, err = data.ReadAt(b, e.End()-1)
+		if err != nil || b[0] != END_BYTE {
+			return false
+		}
+	}
+	return true
+}
+
+// the first entry with a timestamp >= ts, len(entries) if there is none
+func searchTimestamp(entries []IndexEntry, ts uint32) int {
+	return sort.Search(len(entries), func(i int) bool {
+		return entries[i].Timestamp >= ts
+	})
+}
+
+// the number of entries which end at or before offset
+func searchEnd(entries []IndexEntry, offset int64) int {
+	return sort.Search(len(entries), func(i int) bool {
+		return entries[i].End() > offset
+	})
+}
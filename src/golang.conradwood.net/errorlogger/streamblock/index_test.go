@@ -0,0 +1,201 @@
+package streamblock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// write count timestamped blocks, starting with block number "from", through an indexed writer
+func write_indexed(t *testing.T, datafile string, from, count int) {
+	idx, err := OpenIndex(datafile, timestampOf)
+	if err != nil {
+		t.Fatalf("failed to open index: %s", err)
+	}
+	defer idx.Close()
+	f, err := os.OpenFile(datafile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatalf("failed to open datafile: %s", err)
+	}
+	defer f.Close()
+	w := NewIndexedBlockWriter(f, idx, FRAMING_CHECKSUM)
+	for i := from; i < from+count; i++ {
+		_, err = w.Write(timestamped(i))
+		if err != nil {
+			t.Fatalf("failed to write: %s", err)
+		}
+	}
+}
+
+func check_index(t *testing.T, datafile string, count int) {
+	idx, err := OpenIndex(datafile, timestampOf)
+	if err != nil {
+		t.Fatalf("failed to open index: %s", err)
+	}
+	defer idx.Close()
+	entries := idx.Entries()
+	if len(entries) != count {
+		t.Fatalf("expected %d index entries, got %d", count, len(entries))
+	}
+	f, err := os.Open(datafile)
+	if err != nil {
+		t.Fatalf("failed to open datafile: %s", err)
+	}
+	defer f.Close()
+	for i, e := range entries {
+		if e.Timestamp != uint32(100+2*i) {
+			t.Errorf("entry %d: expected timestamp %d, got %d", i, 100+2*i, e.Timestamp)
+		}
+	}
+	nr := NewIndexedBlockReader(f, entries)
+	for _, n := range []int{0, 7, count / 2, count - 1} {
+		err = nr.SeekToTime(uint32(100+2*n)-1, timestampOf)
+		if err != nil {
+			t.Fatalf("failed to seek: %s", err)
+		}
+		got, err := nr.ReadBlock()
+		if err != nil {
+			t.Fatalf("failed to read: %s", err)
+		}
+		if !issame(got, timestamped(n)) {
+			t.Errorf("seeking to time of block %d read \"%s\"", n, hexstr(got))
+		}
+		err = nr.SeekFromEnd(count - n)
+		if err != nil {
+			t.Fatalf("failed to seek: %s", err)
+		}
+		got, err = nr.ReadBlock()
+		if err != nil {
+			t.Fatalf("failed to read: %s", err)
+		}
+		if !issame(got, timestamped(n)) {
+			t.Errorf("seeking %d from end read \"%s\"", count-n, hexstr(got))
+		}
+	}
+	got, err := nr.ReadLastBlock()
+	for i := count - 1; i >= 0; i-- {
+		if err != nil {
+			t.Fatalf("failed to read block %d backwards: %s", i, err)
+		}
+		if !issame(got, timestamped(i)) {
+			t.Errorf("reading backwards, expected block %d, got \"%s\"", i, hexstr(got))
+		}
+		got, err = nr.ReadPreviousBlock()
+	}
+	if err == nil {
+		t.Errorf("read beyond first block")
+	}
+}
+
+func TestIndex(t *testing.T) {
+	datafile := filepath.Join(t.TempDir(), "proto.log")
+	write_indexed(t, datafile, 0, 300)
+	check_index(t, datafile, 300)
+
+	// missing index is rebuilt
+	err := os.Remove(IndexFilename(datafile))
+	if err != nil {
+		t.Fatalf("failed to remove index: %s", err)
+	}
+	check_index(t, datafile, 300)
+
+	// blocks written without index and a trailing partial block
+	f, err := os.OpenFile(datafile, os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatalf("failed to open datafile: %s", err)
+	}
+	w := NewBlockWriter(f)
+	for i := 300; i < 320; i++ {
+		w.Write(timestamped(i))
+	}
+	f.Write([]byte{START_BYTE, 1, 2, 3})
+	f.Close()
+	check_index(t, datafile, 320)
+
+	// appending after the partial block
+	write_indexed(t, datafile, 320, 5)
+	check_index(t, datafile, 325)
+
+	// data file replaced by a shorter one
+	err = os.Remove(datafile)
+	if err != nil {
+		t.Fatalf("failed to remove datafile: %s", err)
+	}
+	f, err = os.Create(datafile)
+	if err != nil {
+		t.Fatalf("failed to create datafile: %s", err)
+	}
+	w = NewBlockWriter(f)
+	for i := 0; i < 10; i++ {
+		w.Write(timestamped(i))
+	}
+	f.Close()
+	check_index(t, datafile, 10)
+}
+
+// a writer which writes only part of the data and fails once "fail" is set
+type tornWriter struct {
+	w    *os.File
+	fail bool
+}
+
+func (t *tornWriter) Write(b []byte) (int, error) {
+	if !t.fail {
+		return t.w.Write(b)
+	}
+	t.fail = false
+	n, err := t.w.Write(b[:len(b)/2])
+	if err != nil {
+		return n, err
+	}
+	return n, fmt.Errorf("no space left on device")
+}
+
+func TestIndexTornWrite(t *testing.T) {
+	datafile := filepath.Join(t.TempDir(), "proto.log")
+	idx, err := OpenIndex(datafile, timestampOf)
+	if err != nil {
+		t.Fatalf("failed to open index: %s", err)
+	}
+	defer idx.Close()
+	f, err := os.OpenFile(datafile, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0666)
+	if err != nil {
+		t.Fatalf("failed to open datafile: %s", err)
+	}
+	defer f.Close()
+	tw := &tornWriter{w: f}
+	w := NewIndexedBlockWriter(tw, idx, FRAMING_CHECKSUM)
+	for i := 0; i < 10; i++ {
+		tw.fail = i == 5
+		_, err = w.Write(timestamped(i))
+		if (err != nil) != (i == 5) {
+			t.Fatalf("block %d: unexpected result of write: %v", i, err)
+		}
+	}
+
+	// the index of the running writer skips the torn block
+	entries := idx.Entries()
+	if len(entries) != 9 {
+		t.Fatalf("expected 9 index entries, got %d", len(entries))
+	}
+	nr := NewIndexedBlockReader(f, entries)
+	got, err := nr.ReadLastBlock()
+	for _, i := range []int{9, 8, 7, 6, 4, 3, 2, 1, 0} {
+		if err != nil {
+			t.Fatalf("failed to read block %d backwards: %s", i, err)
+		}
+		if !issame(got, timestamped(i)) {
+			t.Fatalf("expected block %d, read \"%s\"", i, hexstr(got))
+		}
+		got, err = nr.ReadPreviousBlock()
+	}
+	err = nr.SeekToTime(uint32(100+2*7)-1, timestampOf)
+	if err != nil {
+		t.Fatalf("failed to seek: %s", err)
+	}
+	got, err = nr.ReadBlock()
+	if err != nil || !issame(got, timestamped(7)) {
+		t.Errorf("seeking to time of block 7 read \"%s\" (%v)", hexstr(got), err)
+	}
+}
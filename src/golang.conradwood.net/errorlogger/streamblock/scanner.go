@@ -0,0 +1,69 @@
+package streamblock
+
+import (
+	"fmt"
+	"io"
+)
+
+// a block or a damaged part of a stream, as found by a Scanner
+type ScannedBlock struct {
+	Offset int64  // where the block (or damage) starts
+	Length int64  // bytes in the stream, including markers
+	Data   []byte // the unescaped block, nil if Err is set
+	Err    error  // nil for valid blocks, otherwise a *CorruptBlockError
+}
+
+// scans a stream from the beginning and reports every block and every damaged part of it
+type Scanner struct {
+	br      *BlockReader
+	pending *ScannedBlock
+}
+
+func NewScanner(r io.Reader) *Scanner {
+	return &Scanner{br: NewBlockReader(r)}
+}
+
+// the next block or damaged part of the stream. returns io.EOF at the end of the stream
+func (s *Scanner) Next() (*ScannedBlock, error) {
+	if s.pending != nil {
+		res := s.pending
+		s.pending = nil
+		return res, nil
+	}
+	br := s.br
+	start := br.pos
+	b, err := br.ReadBlock()
+	var res *ScannedBlock
+	if err == io.ErrUnexpectedEOF {
+		res = &ScannedBlock{
+			Offset: br.block_start,
+			Length: br.pos - br.block_start,
+			Err:    &CorruptBlockError{Offset: br.block_start, Reason: "truncated block at end of stream"},
+		}
+	} else if err == io.EOF {
+		if br.skipped == 0 {
+			return nil, io.EOF
+		}
+		return &ScannedBlock{
+			Offset: start,
+			Length: br.skipped,
+			Err:    &CorruptBlockError{Offset: start, Reason: fmt.Sprintf("%d bytes outside of blocks at end of stream", br.skipped)},
+		}, nil
+	} else if IsCorruptBlock(err) {
+		res = &ScannedBlock{Offset: br.block_start, Length: br.pos - br.block_start, Err: err}
+	} else if err != nil {
+		return nil, err
+	} else {
+		res = &ScannedBlock{Offset: br.block_start, Length: br.pos - br.block_start, Data: b}
+	}
+	if br.skipped == 0 {
+		return res, nil
+	}
+	// bytes before the block are reported first
+	s.pending = res
+	return &ScannedBlock{
+		Offset: start,
+		Length: br.skipped,
+		Err:    &CorruptBlockError{Offset: start, Reason: fmt.Sprintf("%d bytes outside of blocks", br.skipped)},
+	}, nil
+}
@@ -0,0 +1,106 @@
+package streamblock
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestScanner(t *testing.T) {
+	f := deterministic1
+	out := &bytes.Buffer{}
+	w := NewBlockWriterWithFraming(out, FRAMING_CHECKSUM)
+	w.Write(f(0))
+	garbage_at := out.Len()
+	out.Write([]byte{7, 8, 9})
+	w.Write(f(1))
+	unterminated_at := out.Len()
+	out.Write([]byte{START_BYTE, 4, 5, 6})
+	w.Write(f(2))
+	corrupt_at := out.Len()
+	w.Write(f(3))
+	w.Write(f(4))
+	truncated_at := out.Len()
+	out.Write([]byte{START_BYTE, 4, 5, 6})
+	z := out.Bytes()
+	z[corrupt_at+10] ^= 0x40
+
+	type expect struct {
+		offset int64
+		block  int // -1 for damage
+	}
+	expected := []expect{
+		{0, 0},
+		{int64(garbage_at), -1},
+		{int64(garbage_at) + 3, 1},
+		{int64(unterminated_at), -1},
+		{int64(unterminated_at) + 4, 2},
+		{int64(corrupt_at), -1},
+		{-1, 4},
+		{int64(truncated_at), -1},
+	}
+	s := NewScanner(bytes.NewReader(z))
+	for i, e := range expected {
+		sb, err := s.Next()
+		if err != nil {
+			t.Fatalf("scan %d: %s", i, err)
+		}
+		if e.offset != -1 && sb.Offset != e.offset {
+			t.Errorf("scan %d: expected offset %d, got %d (%v)", i, e.offset, sb.Offset, sb.Err)
+		}
+		if e.block == -1 {
+			if !IsCorruptBlock(sb.Err) {
+				t.Errorf("scan %d: expected damage at %d, got %v", i, sb.Offset, sb.Err)
+			}
+			continue
+		}
+		if sb.Err != nil {
+			t.Errorf("scan %d: expected block %d, got %s", i, e.block, sb.Err)
+			continue
+		}
+		if !issame(sb.Data, f(e.block)) {
+			t.Errorf("scan %d: expected block %d, got \"%s\"", i, e.block, hexstr(sb.Data))
+		}
+	}
+	_, err := s.Next()
+	if err != io.EOF {
+		t.Errorf("expected EOF, got %v", err)
+	}
+
+	// reading backwards ignores the truncated block and reports the damage
+	nr := NewSeekableBlockReader(bytes.NewReader(z))
+	got, err := nr.ReadLastBlock()
+	if err != nil || !issame(got, f(4)) {
+		t.Fatalf("expected last block to be block 4, got \"%s\" (%v)", hexstr(got), err)
+	}
+	var blocks []int
+	for {
+		got, err = nr.ReadPreviousBlock()
+		if err == io.EOF {
+			break
+		}
+		if IsCorruptBlock(err) {
+			blocks = append(blocks, -1)
+			continue
+		}
+		if err != nil {
+			t.Fatalf("failed to read backwards: %s", err)
+		}
+		for i := 0; i < 5; i++ {
+			if issame(got, f(i)) {
+				blocks = append(blocks, i)
+			}
+		}
+	}
+	// the unterminated block and the garbage are not between END_BYTE and START_BYTE and therefore skipped
+	expect_blocks := []int{-1, 2, 1, 0}
+	if len(blocks) != len(expect_blocks) {
+		t.Fatalf("reading backwards, expected %v, got %v", expect_blocks, blocks)
+	}
+	for i := range blocks {
+		if blocks[i] != expect_blocks[i] {
+			t.Errorf("reading backwards, expected %v, got %v", expect_blocks, blocks)
+			break
+		}
+	}
+}
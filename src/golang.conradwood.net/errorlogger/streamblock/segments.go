@@ -0,0 +1,577 @@
+package streamblock
+
+/*
+a block stream stored as a sequence of files ("segments").
+
+Blocks are appended to the active segment, which is the file with the base name (e.g. proto.log). Once it is
+full, it is sealed: it is renamed to basename.N (N counting up, e.g. proto.log.000001) and a new active segment is
+started. Files rotated by logrotate (proto.log.1, proto.log.2, ...) are read as sealed segments, too. Sealed segments are deleted once they exceed the retention policy. Each segment has its own index, if
+indices are enabled.
+
+A SegmentedReader reads across all segments as if they were one stream.
+*/
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	expire_interval = time.Minute // how often Write() checks for segments to expire
+)
+
+type SegmentOptions struct {
+	MaxMB       int           // seal the active segment once it reaches this size. 0 never seals it
+	MaxSegments int           // delete the oldest sealed segments if there are more than this. 0 keeps all
+	MaxAge      time.Duration // delete sealed segments which were last written to longer ago than this. 0 keeps them
+	Framing     Framing
+	Index       bool          // maintain an index for each segment
+	Timestamp   TimestampFunc // timestamps blocks for the index
+	// fsync the active segment after every Write() or WriteBlocks(). Otherwise, with SyncInterval, every SyncInterval.
+	// Without either it is left to the operating system. Indices are not synced, they are rebuilt if they do not match
+	SyncWrites   bool
+	SyncInterval time.Duration
+}
+
+// writes blocks to the active segment
+type SegmentedWriter struct {
+	lock        sync.Mutex
+	basename    string
+	opts        SegmentOptions
+	maxbytes    int64
+	file        *os.File
+	size        int64
+	idx         *Index // nil without index
+	w           io.Writer
+	sealed      []*segment // oldest first
+	last_expire time.Time
+	dirty       bool          // written to since the last fsync
+	stop_sync   chan struct{} // stops periodic syncs, nil if there are none
+}
+
+// a sealed segment
+type segment struct {
+	filename string
+	seq      int          // 0 for files which were moved here by something else, e.g. proto.log.1 from logrotate
+	rotation int          // the number of such a file, lower is newer
+	info     os.FileInfo  // identifies the file across renames
+	entries  []IndexEntry // nil without index
+}
+
+// orders segments with the same modification time, e.g. written within the same clock tick
+func (s *segment) order() int {
+	if s.seq > 0 {
+		return s.seq
+	}
+	return -s.rotation
+}
+
+// true if s is the same, unchanged, file as the one st describes
+func (s *segment) unchanged(st os.FileInfo) bool {
+	return s.info != nil && os.SameFile(s.info, st) && s.info.Size() == st.Size()
+}
+
+// open the segments of basename for writing. An existing file basename continues as active segment
+func OpenSegmentedWriter(basename string, opts *SegmentOptions) (*SegmentedWriter, error) {
+	sw := &SegmentedWriter{basename: basename, opts: *opts, maxbytes: int64(opts.MaxMB) * 1024 * 1024}
+	if sw.opts.Framing == 0 {
+		sw.opts.Framing = FRAMING_PLAIN
+	}
+	err := os.MkdirAll(filepath.Dir(basename), 0777)
+	if err != nil {
+		return nil, err
+	}
+	sw.sealed, err = sw.findSealed()
+	if err != nil {
+		return nil, err
+	}
+	if sw.opts.Index {
+		for _, s := range sw.sealed {
+			idx, err := OpenIndex(s.filename, sw.opts.Timestamp)
+			if err != nil {
+				return nil, err
+			}
+			s.entries = idx.Entries()
+			idx.Close()
+		}
+	}
+	err = sw.openActive()
+	if err != nil {
+		return nil, err
+	}
+	sw.expire()
+	if !sw.opts.SyncWrites && sw.opts.SyncInterval > 0 {
+		sw.stop_sync = make(chan struct{})
+		go sw.syncPeriodically(sw.stop_sync)
+	}
+	return sw, nil
+}
+
+// the sealed segments on disk, oldest first.
+// Besides our own segments (basename.000001, ...) this finds files rotated by something else (basename.1, basename.2, ...),
+// which are numbered the other way round, so segments are ordered by the time they were last written to
+func (sw *SegmentedWriter) findSealed() ([]*segment, error) {
+	names, err := filepath.Glob(escapeGlob(sw.basename) + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var res []*segment
+	for _, name := range names {
+		suffix := strings.TrimPrefix(name, sw.basename+".")
+		num, err := strconv.Atoi(suffix)
+		if err != nil || num <= 0 {
+			// not a segment, e.g. an index or a compressed rotation
+			continue
+		}
+		st, err := os.Stat(name)
+		if err != nil {
+			continue
+		}
+		s := &segment{filename: name, info: st}
+		if suffix == fmt.Sprintf("%06d", num) {
+			s.seq = num
+		} else {
+			s.rotation = num
+		}
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		ti, tj := res[i].info.ModTime(), res[j].info.ModTime()
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return res[i].order() < res[j].order()
+	})
+	return res, nil
+}
+
+func (sw *SegmentedWriter) openActive() error {
+	var err error
+	sw.file, err = os.OpenFile(sw.basename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	st, err := sw.file.Stat()
+	if err != nil {
+		sw.file.Close()
+		return err
+	}
+	sw.size = st.Size()
+	if !sw.opts.Index {
+		sw.w = NewBlockWriterWithFraming(sw.file, sw.opts.Framing)
+		return nil
+	}
+	sw.idx, err = OpenIndex(sw.basename, sw.opts.Timestamp)
+	if err != nil {
+		sw.file.Close()
+		return err
+	}
+	sw.w = NewIndexedBlockWriter(sw.file, sw.idx, sw.opts.Framing)
+	return nil
+}
+
+// write a block to the active segment, sealing it first if it is full
+func (sw *SegmentedWriter) Write(block []byte) (int, error) {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	n, err := sw.write(block)
+	if err != nil {
+		return n, err
+	}
+	if sw.opts.SyncWrites {
+		err = sw.sync()
+	}
+	return n, err
+}
+
+// write several blocks, with only one fsync for all of them. returns the number of blocks written
+func (sw *SegmentedWriter) WriteBlocks(blocks [][]byte) (int, error) {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	for i, block := range blocks {
+		_, err := sw.write(block)
+		if err != nil {
+			return i, err
+		}
+	}
+	if sw.opts.SyncWrites {
+		err := sw.sync()
+		if err != nil {
+			return len(blocks), err
+		}
+	}
+	return len(blocks), nil
+}
+
+// must be called with lock held
+func (sw *SegmentedWriter) write(block []byte) (int, error) {
+	if sw.file == nil {
+		return 0, fmt.Errorf("segments of %s are closed", sw.basename)
+	}
+	if sw.maxbytes > 0 && sw.size >= sw.maxbytes {
+		err := sw.seal()
+		if err != nil {
+			fmt.Printf("[streamblock] failed to seal %s: %s\n", sw.basename, err)
+			if sw.file == nil {
+				return 0, err
+			}
+		}
+	} else if time.Since(sw.last_expire) > expire_interval {
+		sw.expire()
+	}
+	n, err := sw.w.Write(block)
+	sw.size = sw.size + int64(n)
+	sw.dirty = true
+	return n, err
+}
+
+// fsync the active segment every SyncInterval until stop is closed
+func (sw *SegmentedWriter) syncPeriodically(stop chan struct{}) {
+	t := time.NewTicker(sw.opts.SyncInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		sw.lock.Lock()
+		sw.sync()
+		sw.lock.Unlock()
+	}
+}
+
+// fsync the active segment if something was written since the last sync. must be called with lock held
+func (sw *SegmentedWriter) sync() error {
+	if !sw.dirty || sw.file == nil {
+		return nil
+	}
+	err := sw.file.Sync()
+	if err != nil {
+		fmt.Printf("[streamblock] failed to sync %s: %s\n", sw.basename, err)
+		return err
+	}
+	sw.dirty = false
+	return nil
+}
+
+// sync (unless it is left to the operating system) and close the active segment. must be called with lock held
+func (sw *SegmentedWriter) closeActive() error {
+	if sw.idx != nil {
+		sw.idx.Close()
+		sw.idx = nil
+	}
+	if sw.file == nil {
+		return nil
+	}
+	if sw.opts.SyncWrites || sw.opts.SyncInterval > 0 {
+		sw.sync()
+	}
+	err := sw.file.Close()
+	sw.file = nil
+	sw.dirty = false
+	return err
+}
+
+// rename the active segment to the next sequence number and start a new one. must be called with lock held
+func (sw *SegmentedWriter) seal() error {
+	s := &segment{seq: 1}
+	for _, o := range sw.sealed {
+		if o.seq >= s.seq {
+			s.seq = o.seq + 1
+		}
+	}
+	s.filename = fmt.Sprintf("%s.%06d", sw.basename, s.seq)
+	err := os.Rename(sw.basename, s.filename)
+	if err != nil {
+		// keep writing to the active segment
+		return err
+	}
+	s.info, err = os.Stat(s.filename)
+	if err != nil {
+		// looked up by name only
+		s.info = nil
+	}
+	fmt.Printf("[streamblock] sealed %s as %s (%d bytes)\n", sw.basename, s.filename, sw.size)
+	if sw.idx != nil {
+		s.entries = sw.idx.Entries()
+	}
+	sw.closeActive()
+	if sw.opts.Index {
+		err = os.Rename(IndexFilename(sw.basename), IndexFilename(s.filename))
+		if err != nil {
+			// rebuilt when the segment is opened next time
+			fmt.Printf("[streamblock] failed to rename index of %s: %s\n", s.filename, err)
+		}
+	}
+	sw.sealed = append(sw.sealed, s)
+	err = sw.openActive()
+	if err != nil {
+		return err
+	}
+	sw.expire()
+	return nil
+}
+
+// delete sealed segments according to the retention policy. must be called with lock held (or before the writer is used)
+func (sw *SegmentedWriter) expire() {
+	sw.last_expire = time.Now()
+	var keep []*segment
+	for i, s := range sw.sealed {
+		expired := sw.opts.MaxSegments > 0 && len(sw.sealed)-i > sw.opts.MaxSegments
+		if !expired && sw.opts.MaxAge != 0 {
+			st, err := os.Stat(s.filename)
+			expired = err == nil && time.Since(st.ModTime()) > sw.opts.MaxAge
+		}
+		if !expired {
+			keep = append(keep, s)
+			continue
+		}
+		fmt.Printf("[streamblock] removing expired segment %s\n", s.filename)
+		err := os.Remove(s.filename)
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Printf("[streamblock] failed to remove %s: %s\n", s.filename, err)
+			keep = append(keep, s)
+			continue
+		}
+		os.Remove(IndexFilename(s.filename))
+	}
+	sw.sealed = keep
+}
+
+// close the active segment and open it again, e.g. after an external logrotate moved it.
+// Sealed segments are looked up again, so that a moved file which is named like a segment is read as one. Known segments
+// are recognised by file identity rather than name, as logrotate renames them (proto.log.1 becomes proto.log.2). Any other
+// file is indexed from scratch. Writes wait until the segment is reopened
+func (sw *SegmentedWriter) Reopen() error {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	sw.closeActive()
+	err := sw.openActive()
+	if err != nil {
+		return err
+	}
+	sealed, err := sw.findSealed()
+	if err != nil {
+		// keep the segments known so far
+		return err
+	}
+	known := make([]*segment, len(sealed))
+	for i, s := range sealed {
+		for _, k := range sw.sealed {
+			if k.unchanged(s.info) {
+				known[i] = k
+				break
+			}
+		}
+	}
+	// move the indices of renamed segments along with them, oldest first, so none is overwritten before it is moved
+	for i, s := range sealed {
+		k := known[i]
+		if k == nil || k.filename == s.filename || !sw.opts.Index {
+			continue
+		}
+		err := os.Rename(IndexFilename(k.filename), IndexFilename(s.filename))
+		if err != nil {
+			os.Remove(IndexFilename(s.filename))
+		}
+	}
+	sw.sealed = nil
+	for i, s := range sealed {
+		if k := known[i]; k != nil {
+			s.entries = k.entries
+			sw.sealed = append(sw.sealed, s)
+			continue
+		}
+		if sw.opts.Index {
+			// an index with this name belongs to whichever file had the name before
+			os.Remove(IndexFilename(s.filename))
+			idx, err := OpenIndex(s.filename, sw.opts.Timestamp)
+			if err != nil {
+				fmt.Printf("[streamblock] failed to index %s, ignoring it: %s\n", s.filename, err)
+				continue
+			}
+			s.entries = idx.Entries()
+			idx.Close()
+		}
+		sw.sealed = append(sw.sealed, s)
+	}
+	return nil
+}
+
+func (sw *SegmentedWriter) Close() error {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	if sw.stop_sync != nil {
+		close(sw.stop_sync)
+		sw.stop_sync = nil
+	}
+	return sw.closeActive()
+}
+
+// open a reader for all blocks written so far. Blocks written later are not visible to the reader.
+// The reader keeps the segments open, so they may be sealed or expired while it is in use
+func (sw *SegmentedWriter) OpenReader() (*SegmentedReader, error) {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+	sr := &SegmentedReader{}
+	for _, s := range sw.sealed {
+		f, err := os.Open(s.filename)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			sr.Close()
+			return nil, err
+		}
+		st, err := f.Stat()
+		if err != nil {
+			f.Close()
+			sr.Close()
+			return nil, err
+		}
+		sr.addPart(f, st.Size(), s.entries, sw.opts.Index)
+	}
+	f, err := os.Open(sw.basename)
+	if err != nil {
+		sr.Close()
+		return nil, err
+	}
+	var entries []IndexEntry
+	if sw.idx != nil {
+		entries = sw.idx.Entries()
+	}
+	sr.addPart(f, sw.size, entries, sw.opts.Index)
+	return sr, nil
+}
+
+// reads the blocks of all segments as one stream
+type SegmentedReader struct {
+	parts []*readerPart // oldest first
+	cur   int
+}
+type readerPart struct {
+	file *os.File
+	br   *BlockReader
+}
+
+func (sr *SegmentedReader) addPart(f *os.File, size int64, entries []IndexEntry, indexed bool) {
+	r := io.NewSectionReader(f, 0, size)
+	p := &readerPart{file: f}
+	if indexed {
+		p.br = NewIndexedBlockReader(r, entries)
+	} else {
+		p.br = NewSeekableBlockReader(r)
+	}
+	sr.parts = append(sr.parts, p)
+}
+
+func (sr *SegmentedReader) Close() error {
+	var res error
+	for _, p := range sr.parts {
+		err := p.file.Close()
+		if err != nil && res == nil {
+			res = err
+		}
+	}
+	return res
+}
+
+// like BlockReader.ReadBlock(), continuing with the next segment at the end of one.
+// a block which was not completely written at the end of a sealed segment is skipped
+func (sr *SegmentedReader) ReadBlock() ([]byte, error) {
+	for {
+		b, err := sr.parts[sr.cur].br.ReadBlock()
+		if (err == io.EOF || err == io.ErrUnexpectedEOF) && sr.cur < len(sr.parts)-1 {
+			sr.cur++
+			err = sr.parts[sr.cur].br.seekTo(0)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return b, err
+	}
+}
+
+// like BlockReader.ReadPreviousBlock(), continuing with the previous segment at the beginning of one
+func (sr *SegmentedReader) ReadPreviousBlock() ([]byte, error) {
+	for {
+		b, err := sr.parts[sr.cur].br.ReadPreviousBlock()
+		if err == io.EOF && sr.cur > 0 {
+			sr.cur--
+			_, err = sr.parts[sr.cur].br.seekEnd()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return b, err
+	}
+}
+
+// the last block of the last segment which has one
+func (sr *SegmentedReader) ReadLastBlock() ([]byte, error) {
+	sr.cur = len(sr.parts) - 1
+	_, err := sr.parts[sr.cur].br.seekEnd()
+	if err != nil {
+		return nil, err
+	}
+	return sr.ReadPreviousBlock()
+}
+
+// like BlockReader.SeekToTime(), across segments
+func (sr *SegmentedReader) SeekToTime(ts uint32, tsf TimestampFunc) error {
+	for i, p := range sr.parts {
+		if i < len(sr.parts)-1 {
+			last, err := p.lastTimestamp(tsf)
+			if err != nil {
+				return err
+			}
+			if last < ts {
+				continue
+			}
+		}
+		sr.cur = i
+		return p.br.SeekToTime(ts, tsf)
+	}
+	return nil
+}
+
+// the timestamp of the last block in this part which can be timestamped, 0 if there is none
+func (p *readerPart) lastTimestamp(tsf TimestampFunc) (uint32, error) {
+	if p.br.use_index {
+		if len(p.br.index) == 0 {
+			return 0, nil
+		}
+		return p.br.index[len(p.br.index)-1].Timestamp, nil
+	}
+	b, err := p.br.ReadLastBlock()
+	for {
+		if err == io.EOF {
+			return 0, nil
+		}
+		if err != nil && !IsCorruptBlock(err) {
+			return 0, err
+		}
+		if err == nil {
+			ts, terr := tsf(b)
+			if terr == nil {
+				return ts, nil
+			}
+		}
+		b, err = p.br.ReadPreviousBlock()
+	}
+}
+
+func escapeGlob(s string) string {
+	r := strings.NewReplacer("*", "\\*", "?", "\\?", "[", "\\[", "\\", "\\\\")
+	return r.Replace(s)
+}
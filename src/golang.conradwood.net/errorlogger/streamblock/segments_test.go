@@ -0,0 +1,241 @@
+package streamblock
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// write blocks "from" to "to" (exclusive) and seal the active segment every "per" blocks
+func write_segmented(t *testing.T, sw *SegmentedWriter, from, to, per int) {
+	for i := from; i < to; i++ {
+		if i > from && i%per == 0 {
+			sw.lock.Lock()
+			err := sw.seal()
+			sw.lock.Unlock()
+			if err != nil {
+				t.Fatalf("failed to seal: %s", err)
+			}
+		}
+		_, err := sw.Write(timestamped(i))
+		if err != nil {
+			t.Fatalf("failed to write: %s", err)
+		}
+	}
+}
+
+// check that the reader returns blocks from to to (exclusive) in both directions and can seek to each of them
+func check_segmented(t *testing.T, sw *SegmentedWriter, from, to int) {
+	sr, err := sw.OpenReader()
+	if err != nil {
+		t.Fatalf("failed to open reader: %s", err)
+	}
+	defer sr.Close()
+	got, err := sr.ReadLastBlock()
+	for i := to - 1; i >= from; i-- {
+		if err != nil {
+			t.Fatalf("failed to read block %d backwards: %s", i, err)
+		}
+		if !issame(got, timestamped(i)) {
+			t.Fatalf("reading backwards, expected block %d, got \"%s\"", i, hexstr(got))
+		}
+		got, err = sr.ReadPreviousBlock()
+	}
+	if err != io.EOF {
+		t.Errorf("expected EOF before first block, got %v", err)
+	}
+	for _, n := range []int{from, from + 1, (from + to) / 2, to - 1} {
+		err = sr.SeekToTime(uint32(100+2*n)-1, timestampOf)
+		if err != nil {
+			t.Fatalf("failed to seek: %s", err)
+		}
+		for i := n; i < to; i++ {
+			got, err = sr.ReadBlock()
+			if err != nil {
+				t.Fatalf("after seeking to block %d, failed to read block %d: %s", n, i, err)
+			}
+			if !issame(got, timestamped(i)) {
+				t.Fatalf("after seeking to block %d, expected block %d, got \"%s\"", n, i, hexstr(got))
+			}
+		}
+		_, err = sr.ReadBlock()
+		if err != io.EOF {
+			t.Errorf("expected EOF after last block, got %v", err)
+		}
+	}
+	// beyond the last block
+	err = sr.SeekToTime(uint32(100+2*to), timestampOf)
+	if err != nil {
+		t.Fatalf("failed to seek: %s", err)
+	}
+	_, err = sr.ReadBlock()
+	if err != io.EOF {
+		t.Errorf("expected EOF after seeking beyond last block, got %v", err)
+	}
+}
+
+func TestSegments(t *testing.T) {
+	for _, index := range []bool{false, true} {
+		basename := filepath.Join(t.TempDir(), "proto.log")
+		opts := &SegmentOptions{Framing: FRAMING_CHECKSUM, Index: index, Timestamp: timestampOf}
+		sw, err := OpenSegmentedWriter(basename, opts)
+		if err != nil {
+			t.Fatalf("failed to open: %s", err)
+		}
+		write_segmented(t, sw, 0, 50, 10)
+		check_segmented(t, sw, 0, 50)
+		if len(sw.sealed) != 4 {
+			t.Errorf("expected 4 sealed segments, got %d", len(sw.sealed))
+		}
+
+		// a reader does not see blocks written after it was opened, nor is it affected by sealing
+		sr, err := sw.OpenReader()
+		if err != nil {
+			t.Fatalf("failed to open reader: %s", err)
+		}
+		write_segmented(t, sw, 50, 55, 100)
+		got, err := sr.ReadLastBlock()
+		if err != nil || !issame(got, timestamped(49)) {
+			t.Errorf("expected block 49 as last block, got \"%s\" (%v)", hexstr(got), err)
+		}
+		sr.Close()
+		sw.Close()
+
+		// re-opening continues with the existing segments
+		sw, err = OpenSegmentedWriter(basename, opts)
+		if err != nil {
+			t.Fatalf("failed to re-open: %s", err)
+		}
+		check_segmented(t, sw, 0, 55)
+
+		// retention by number
+		sw.opts.MaxSegments = 2
+		write_segmented(t, sw, 55, 60, 56)
+		check_segmented(t, sw, 30, 60)
+		if _, err := os.Stat(basename + ".000001"); !os.IsNotExist(err) {
+			t.Errorf("expected first segment to be removed (%v)", err)
+		}
+		if _, err := os.Stat(IndexFilename(basename + ".000001")); !os.IsNotExist(err) {
+			t.Errorf("expected index of first segment to be removed (%v)", err)
+		}
+
+		// retention by age
+		sw.opts.MaxSegments = 0
+		sw.opts.MaxAge = time.Hour
+		old := time.Now().Add(-2 * time.Hour)
+		os.Chtimes(sw.sealed[0].filename, old, old)
+		sw.lock.Lock()
+		sw.expire()
+		sw.lock.Unlock()
+		check_segmented(t, sw, 40, 60)
+		if len(sw.sealed) != 1 {
+			t.Errorf("expected 1 sealed segment after expiry, got %d", len(sw.sealed))
+		}
+		sw.Close()
+	}
+}
+
+func TestSegmentSize(t *testing.T) {
+	basename := filepath.Join(t.TempDir(), "proto.log")
+	sw, err := OpenSegmentedWriter(basename, &SegmentOptions{MaxMB: 1})
+	if err != nil {
+		t.Fatalf("failed to open: %s", err)
+	}
+	defer sw.Close()
+	sw.maxbytes = 100 // seal quickly
+	block := make([]byte, 40)
+	for i := range block {
+		block[i] = 'a'
+	}
+	for i := 0; i < 10; i++ {
+		block[0] = byte(10 + i)
+		_, err = sw.Write(block)
+		if err != nil {
+			t.Fatalf("failed to write: %s", err)
+		}
+	}
+	// 42 bytes per block, sealed after 3 blocks
+	if len(sw.sealed) != 3 {
+		t.Errorf("expected 3 sealed segments, got %d", len(sw.sealed))
+	}
+	sr, err := sw.OpenReader()
+	if err != nil {
+		t.Fatalf("failed to open reader: %s", err)
+	}
+	defer sr.Close()
+	for i := 0; i < 10; i++ {
+		got, err := sr.ReadBlock()
+		if err != nil {
+			t.Fatalf("failed to read block %d: %s", i, err)
+		}
+		if got[0] != byte(10+i) {
+			t.Errorf("expected block %d, got block %d", i, got[0]-10)
+		}
+	}
+}
+
+func TestSegmentReopen(t *testing.T) {
+	basename := filepath.Join(t.TempDir(), "proto.log")
+	sw, err := OpenSegmentedWriter(basename, &SegmentOptions{Index: true, Timestamp: timestampOf})
+	if err != nil {
+		t.Fatalf("failed to open: %s", err)
+	}
+	defer sw.Close()
+	write_segmented(t, sw, 0, 10, 100)
+	// what an external logrotate does
+	err = os.Rename(basename, basename+".1")
+	if err != nil {
+		t.Fatalf("failed to rename: %s", err)
+	}
+	err = sw.Reopen()
+	if err != nil {
+		t.Fatalf("failed to reopen: %s", err)
+	}
+	write_segmented(t, sw, 10, 20, 100)
+	check_segmented(t, sw, 0, 20)
+	// the second rotation moves proto.log.1 to proto.log.2
+	for _, r := range [][2]string{{basename + ".1", basename + ".2"}, {basename, basename + ".1"}} {
+		err = os.Rename(r[0], r[1])
+		if err != nil {
+			t.Fatalf("failed to rename: %s", err)
+		}
+	}
+	err = sw.Reopen()
+	if err != nil {
+		t.Fatalf("failed to reopen: %s", err)
+	}
+	write_segmented(t, sw, 20, 30, 100)
+	check_segmented(t, sw, 0, 30)
+	sw.Close()
+
+	// and the indices on disk match the rotated files
+	sw, err = OpenSegmentedWriter(basename, &SegmentOptions{Index: true, Timestamp: timestampOf})
+	if err != nil {
+		t.Fatalf("failed to open: %s", err)
+	}
+	defer sw.Close()
+	check_segmented(t, sw, 0, 30)
+}
+
+func TestSegmentSync(t *testing.T) {
+	basename := filepath.Join(t.TempDir(), "proto.log")
+	sw, err := OpenSegmentedWriter(basename, &SegmentOptions{SyncWrites: true})
+	if err != nil {
+		t.Fatalf("failed to open: %s", err)
+	}
+	defer sw.Close()
+	var blocks [][]byte
+	for i := 0; i < 5; i++ {
+		blocks = append(blocks, timestamped(i))
+	}
+	n, err := sw.WriteBlocks(blocks)
+	if err != nil || n != 5 {
+		t.Fatalf("failed to write blocks (%d written): %v", n, err)
+	}
+	if sw.dirty {
+		t.Errorf("segment not synced after write")
+	}
+	check_segmented(t, sw, 0, 5)
+}
@@ -15,21 +15,61 @@ const (
 	ESCAPED_START_BYTE  = 0x02
 	ESCAPED_END_BYTE    = 0x03
 	ESCAPED_ESCAPE_BYTE = 0x04
+	CHECKSUM_MARKER     = 0x05 // following an ESCAPE_BYTE at the beginning of a block: the block carries a checksum
 )
 
 // write in blocks
 type blockWriter struct {
-	w io.Writer
+	w       io.Writer
+	framing Framing
 }
 
-// return a block writer that writes to "w"
+// return a block writer that writes to "w" (unversioned framing, no checksums)
 func NewBlockWriter(w io.Writer) io.Writer {
-	res := &blockWriter{w: w}
+	return NewBlockWriterWithFraming(w, FRAMING_PLAIN)
+}
+
+// return a block writer that writes to "w" with the given framing
+func NewBlockWriterWithFraming(w io.Writer, framing Framing) io.Writer {
+	res := &blockWriter{w: w, framing: framing}
 	return res
 }
 
 // escape and write a block to disk
 func (b *blockWriter) Write(block []byte) (int, error) {
+	n, err := b.w.Write(encode_block(block, b.framing))
+	return n, err
+}
+
+// a block writer which also adds each block to an index
+type indexedBlockWriter struct {
+	w       io.Writer
+	idx     *Index
+	framing Framing
+}
+
+// return a block writer that writes to "w", which must append to the data file of the index
+func NewIndexedBlockWriter(w io.Writer, idx *Index, framing Framing) io.Writer {
+	res := &indexedBlockWriter{w: w, idx: idx, framing: framing}
+	return res
+}
+
+func (b *indexedBlockWriter) Write(block []byte) (int, error) {
+	n, err := b.w.Write(encode_block(block, b.framing))
+	if err != nil {
+		// a partial block is not indexed, but the following blocks start after it
+		b.idx.skip(n)
+		return n, err
+	}
+	err = b.idx.add(n, block)
+	return n, err
+}
+
+// return the escaped block, including start and end markers
+func encode_block(block []byte, framing Framing) []byte {
+	if framing == FRAMING_CHECKSUM {
+		block = checksum_block(block)
+	}
 	wr := make([]byte, len(block))
 	copy(wr, block)
 	for i := len(block) - 1; i >= 0; i-- {
@@ -46,10 +86,12 @@ func (b *blockWriter) Write(block []byte) (int, error) {
 			}
 		}
 	}
+	if framing == FRAMING_CHECKSUM {
+		wr = append([]byte{ESCAPE_BYTE, CHECKSUM_MARKER}, wr...)
+	}
 	wr = append([]byte{START_BYTE}, wr...) // prefix the start-of-block marker with 1
 	wr = append(wr, END_BYTE)              // append the end-of-block marker with 0
-	n, err := b.w.Write(wr)
-	return n, err
+	return wr
 }
 
 // read in blocks
@@ -60,8 +102,20 @@ type BlockReader struct {
 	bytes_in_buf int
 	read_index   int
 	seekable     bool
+	pos          int64 // offset of the byte nextByte() returns next. prevByte() returns the byte before it
+	need_seek    bool  // the underlying reader is not positioned at pos+bytes_in_buf
+	rbuf         []byte
+	rbuf_start   int64 // offset of rbuf[0]
+	rbuf_len     int
+	block_start  int64 // offset of the START_BYTE of the block ReadBlock() read last
+	skipped      int64 // bytes ReadBlock() skipped to find the START_BYTE of the last block
+	index        []IndexEntry
+	use_index    bool
 }
 
+// extract the timestamp from a block. used to locate blocks by time
+type TimestampFunc func(block []byte) (uint32, error)
+
 func NewBlockReader(r io.Reader) *BlockReader {
 	res := &BlockReader{r: r, buf: make([]byte, 8192)}
 	return res
@@ -71,13 +125,27 @@ func NewSeekableBlockReader(r io.ReadSeeker) *BlockReader {
 		seekable: true,
 		r:        r,
 		rs:       r,
-		buf:      make([]byte, 8192)}
+		buf:      make([]byte, 8192),
+		rbuf:     make([]byte, 8192)}
+	return res
+}
+
+// a seekable block reader which uses index entries (see Index.Entries()) to locate blocks instead of scanning the stream.
+// blocks after the last entry can be read with ReadBlock() but are not found by seeking or reading backwards
+func NewIndexedBlockReader(r io.ReadSeeker, entries []IndexEntry) *BlockReader {
+	res := NewSeekableBlockReader(r)
+	res.index = entries
+	res.use_index = true
 	return res
 }
 
-// reads one block and returns it unescaped.position pointer at beginning of next block
+// reads one block and returns it unescaped.position pointer at beginning of next block.
+// returns a *CorruptBlockError if the block is damaged (including a block which is not terminated before the next one starts),
+// the next call continues with the following block.
+// returns io.ErrUnexpectedEOF if the stream ends within a block, e.g. after a torn write
 func (b *BlockReader) ReadBlock() ([]byte, error) {
 	// find block start, marked by unescaped 1
+	b.skipped = 0
 	for {
 		nb, err := b.nextByte()
 		if err != nil {
@@ -86,20 +154,37 @@ func (b *BlockReader) ReadBlock() ([]byte, error) {
 		if nb == START_BYTE {
 			break
 		}
+		b.skipped++
 	}
+	b.block_start = b.pos - 1
 	// rest follows is a block until unescaped 0
 	var res []byte
 	for {
 		nb, err := b.nextByte()
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
 		if err != nil {
-			return res, err
+			return nil, err
 		}
 		if nb == END_BYTE {
 			break
 		}
+		if nb == START_BYTE {
+			// the next block starts here
+			b.unreadByte()
+			return nil, &CorruptBlockError{Offset: b.block_start, Reason: "unterminated block"}
+		}
 		res = append(res, nb)
 	}
-	return unescape_block(res), nil
+	return decode_block(res, b.block_start)
+}
+
+// undo the last nextByte()
+func (b *BlockReader) unreadByte() {
+	b.read_index--
+	b.bytes_in_buf++
+	b.pos--
 }
 func (b *BlockReader) nextByte() (byte, error) {
 get_byte:
@@ -107,9 +192,17 @@ get_byte:
 		res := b.buf[b.read_index]
 		b.read_index++
 		b.bytes_in_buf--
+		b.pos++
 		return res, nil
 	}
 
+	if b.need_seek {
+		_, err := b.rs.Seek(b.pos, io.SeekStart)
+		if err != nil {
+			return 0, err
+		}
+		b.need_seek = false
+	}
 	n, err := b.r.Read(b.buf)
 	if err != nil {
 		return 0, err
@@ -122,8 +215,94 @@ get_byte:
 	goto get_byte
 }
 
+// position the pointer at the first block with a timestamp >= ts, so that ReadBlock() returns it.
+// blocks must be stored in chronological order. Only a logarithmic number of blocks is read and passed to tsf,
+// blocks for which tsf returns an error are skipped. With an index, the timestamps of the index are used instead
+func (br *BlockReader) SeekToTime(ts uint32, tsf TimestampFunc) error {
+	if !br.seekable {
+		return fmt.Errorf("this blockreader is not seekable")
+	}
+	if br.use_index {
+		i := searchTimestamp(br.index, ts)
+		if i < len(br.index) {
+			return br.seekTo(br.index[i].Offset)
+		}
+		return br.seekTo(br.indexEnd())
+	}
+	size, err := br.rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	lo := int64(0)
+	hi := size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, bts, err := br.timestampAfter(mid, tsf)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			hi = mid
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if bts >= ts {
+			hi = mid
+		} else {
+			lo = start + 1
+		}
+	}
+	return br.seekTo(lo)
+}
+
+// find the first block starting at or after offset and return its start and timestamp
+func (br *BlockReader) timestampAfter(offset int64, tsf TimestampFunc) (int64, uint32, error) {
+	err := br.seekTo(offset)
+	if err != nil {
+		return 0, 0, err
+	}
+	for {
+		b, err := br.ReadBlock()
+		if IsCorruptBlock(err) {
+			continue
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		ts, err := tsf(b)
+		if err != nil {
+			continue
+		}
+		return br.block_start, ts, nil
+	}
+}
+
+// position the pointer at offset for ReadBlock()
+func (br *BlockReader) seekTo(offset int64) error {
+	_, err := br.rs.Seek(offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+	br.bytes_in_buf = 0
+	br.read_index = 0
+	br.need_seek = false
+	br.pos = offset
+	return nil
+}
+
 // position pointer at beginning of block n from end of file
 func (br *BlockReader) SeekFromEnd(n int) error {
+	if br.use_index {
+		if n < 0 {
+			n = 0 - n
+		}
+		if n > len(br.index) {
+			return io.EOF
+		}
+		if n == 0 {
+			return br.seekTo(br.indexEnd())
+		}
+		return br.seekTo(br.index[len(br.index)-n].Offset)
+	}
 	return br.SeekFromEndForBlocks(n, func(b []byte) bool { return true })
 }
 
@@ -136,13 +315,16 @@ func (br *BlockReader) SeekFromEndForBlocks(n int, f func(b []byte) bool) error
 		n = 0 - n
 	}
 	//	fmt.Printf("Seeking to %d\n", n)
-	_, err := br.rs.Seek(-1, io.SeekEnd) // position at end
+	_, err := br.seekEnd()
 	if err != nil {
 		return err
 	}
 	packets_skipped := 0
 	for {
 		b, err := br.ReadPreviousBlock()
+		if IsCorruptBlock(err) {
+			continue
+		}
 		if err != nil {
 			return err
 		}
@@ -156,8 +338,13 @@ func (br *BlockReader) SeekFromEndForBlocks(n int, f func(b []byte) bool) error
 	}
 }
 
-// read the block that _ends_ before or at the current position. position the seek pointer at the beginnning-1 of the block
+// read the block that _ends_ before the current position. position the pointer at the beginning of the block.
+// returns a *CorruptBlockError if the block is damaged (including a block without START_BYTE), the next call continues with the block before it.
+// bytes after the last END_BYTE, e.g. a block which was not completely written, are ignored
 func (br *BlockReader) ReadPreviousBlock() ([]byte, error) {
+	if br.use_index {
+		return br.readPreviousIndexed()
+	}
 	var cur_block []byte
 	// find end of block
 	for {
@@ -173,72 +360,158 @@ func (br *BlockReader) ReadPreviousBlock() ([]byte, error) {
 	// find start of block
 	for {
 		b, err := br.prevByte()
+		if err == io.EOF {
+			return nil, &CorruptBlockError{Offset: 0, Reason: "block without start at beginning of stream"}
+		}
 		if err != nil {
 			return nil, err
 		}
 		if b == START_BYTE {
 			break
 		}
-		cur_block = append([]byte{b}, cur_block...)
+		if b == END_BYTE {
+			// end of the previous block. the next call continues from here
+			br.pos++
+			return nil, &CorruptBlockError{Offset: br.pos, Reason: "block without start"}
+		}
+		cur_block = append(cur_block, b)
+	}
+	// bytes were collected backwards
+	for i, j := 0, len(cur_block)-1; i < j; i, j = i+1, j-1 {
+		cur_block[i], cur_block[j] = cur_block[j], cur_block[i]
+	}
+	return decode_block(cur_block, br.pos)
+}
+
+// read the indexed block that ends before the current position and position the pointer at its beginning
+func (br *BlockReader) readPreviousIndexed() ([]byte, error) {
+	i := searchEnd(br.index, br.pos)
+	if i == 0 {
+		return nil, io.EOF
+	}
+	e := br.index[i-1]
+	err := br.seekTo(e.Offset)
+	if err != nil {
+		return nil, err
+	}
+	b, rerr := br.ReadBlock()
+	err = br.seekTo(e.Offset)
+	if err != nil {
+		return nil, err
+	}
+	if rerr != nil {
+		return nil, rerr
+	}
+	return b, nil
+}
+
+// end of the last indexed block
+func (br *BlockReader) indexEnd() int64 {
+	if len(br.index) == 0 {
+		return 0
 	}
-	return unescape_block(cur_block), nil
+	return br.index[len(br.index)-1].End()
 }
 
 // given data as read, return as user expects it
-func unescape_block(read []byte) []byte {
+func unescape_block(read []byte) ([]byte, error) {
 	res := make([]byte, len(read))
 	escaped := false
 	n := 0
 	for _, b := range read {
-		if b == ESCAPE_BYTE {
+		if b == ESCAPE_BYTE && !escaped {
 			escaped = true
 			continue
 		}
 		if escaped {
-			b = escaped_byte_to_normal(b)
+			nb, err := escaped_byte_to_normal(b)
+			if err != nil {
+				return nil, err
+			}
+			b = nb
 			escaped = false
 		}
 		res[n] = b
 		n++
 
 	}
-	return res[:n]
+	if escaped {
+		return nil, fmt.Errorf("block ends with escape byte")
+	}
+	return res[:n], nil
 }
 
-func escaped_byte_to_normal(b byte) byte {
+func escaped_byte_to_normal(b byte) (byte, error) {
 	if b == ESCAPED_START_BYTE {
-		return START_BYTE
+		return START_BYTE, nil
 	} else if b == ESCAPED_END_BYTE {
-		return END_BYTE
+		return END_BYTE, nil
 	} else if b == ESCAPED_ESCAPE_BYTE {
-		return ESCAPE_BYTE
+		return ESCAPE_BYTE, nil
 	}
-	fmt.Printf("Invalid escaped byte 0x%02X\n", b)
-	return b
+	return b, fmt.Errorf("invalid escaped byte 0x%02X", b)
 }
 
 // seek to end and readpreviousblock
 func (br *BlockReader) ReadLastBlock() ([]byte, error) {
-	_, err := br.rs.Seek(-1, io.SeekEnd) // position at end
+	_, err := br.seekEnd()
 	if err != nil {
 		return nil, err
 	}
 	return br.ReadPreviousBlock()
 }
 
-// read a byte and position pointer at the byte BEFORE the one read
-func (b *BlockReader) prevByte() (byte, error) {
-	// test, most inefficient ever implementation
-	buf := make([]byte, 1)
-	_, err := b.rs.Read(buf)
+// position at the end of the stream
+func (br *BlockReader) seekEnd() (int64, error) {
+	if br.use_index {
+		end := br.indexEnd()
+		return end, br.seekTo(end)
+	}
+	size, err := br.rs.Seek(0, io.SeekEnd)
 	if err != nil {
 		return 0, err
 	}
-	_, err = b.rs.Seek(-2, io.SeekCurrent)
+	return size, br.seekTo(size)
+}
+
+// read the byte before the current position and position the pointer at the byte read.
+// reads through a reverse read-ahead buffer, the counterpart of the buffer nextByte() uses
+func (b *BlockReader) prevByte() (byte, error) {
+	if b.pos <= 0 {
+		return 0, io.EOF
+	}
+	if b.pos <= b.rbuf_start || b.pos > b.rbuf_start+int64(b.rbuf_len) {
+		err := b.fillReverseBuffer()
+		if err != nil {
+			return 0, err
+		}
+	}
+	// the forward buffer does not follow backward movement
+	b.bytes_in_buf = 0
+	b.need_seek = true
+	b.pos--
+	return b.rbuf[b.pos-b.rbuf_start], nil
+}
+
+// fill the reverse buffer with the bytes before the current position
+func (b *BlockReader) fillReverseBuffer() error {
+	start := b.pos - int64(len(b.rbuf))
+	if start < 0 {
+		start = 0
+	}
+	_, err := b.rs.Seek(start, io.SeekStart)
 	if err != nil {
-		return 0, err
+		return err
+	}
+	n, err := io.ReadFull(b.rs, b.rbuf[:b.pos-start])
+	b.need_seek = true
+	if err != nil {
+		b.rbuf_len = 0
+		return err
 	}
-	return buf[0], nil
+	b.rbuf_start = start
+	b.rbuf_len = n
+	return nil
 }
 func hexstr(a []byte) string {
 	s := ""
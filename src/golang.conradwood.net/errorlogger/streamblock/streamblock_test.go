@@ -2,6 +2,10 @@ package streamblock
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -147,9 +151,14 @@ func checkposread(t *testing.T, f func(b int) []byte) {
 }
 
 func checkSame(t *testing.T, z []byte) {
+	for _, framing := range []Framing{FRAMING_PLAIN, FRAMING_CHECKSUM} {
+		checkSameFraming(t, z, framing)
+	}
+}
+func checkSameFraming(t *testing.T, z []byte, framing Framing) {
 	t.Logf("checksame: "+hexstr(z)+" %v", z)
 	out := &bytes.Buffer{}
-	NewBlockWriter(out).Write(z)
+	NewBlockWriterWithFraming(out, framing).Write(z)
 
 	a := out.Bytes()
 	out = &bytes.Buffer{}
@@ -177,3 +186,233 @@ func issame(a, b []byte) bool {
 	}
 	return true
 }
+
+func TestReadAllPrevious(t *testing.T) {
+	f := deterministic1
+	max := 20
+	z, err := write_blocks(max, f)
+	if err != nil {
+		t.Errorf("failed to write: %s", err)
+		return
+	}
+	nr := NewSeekableBlockReader(bytes.NewReader(z))
+	got, err := nr.ReadLastBlock()
+	for i := max - 1; i >= 0; i-- {
+		if err != nil {
+			t.Errorf("failed to read block %d: %s", i, err)
+			return
+		}
+		expect := f(i)
+		if !issame(got, expect) {
+			t.Errorf("Mismatch: block %d, expected\n\"%s\", but got\n\"%s\"\n", i, hexstr(expect), hexstr(got))
+		}
+		got, err = nr.ReadPreviousBlock()
+	}
+	if err == nil {
+		t.Errorf("read beyond first block: \"%s\"", hexstr(got))
+	}
+}
+
+// blocks with a timestamp of 100+2*n in the first four bytes
+func timestamped(n int) []byte {
+	ts := uint32(100 + 2*n)
+	res := []byte{byte(ts >> 24), byte(ts >> 16), byte(ts >> 8), byte(ts)}
+	return append(res, deterministic1(n)...)
+}
+func timestampOf(b []byte) (uint32, error) {
+	if len(b) < 4 {
+		return 0, fmt.Errorf("block too short")
+	}
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3]), nil
+}
+
+func TestSeekToTime(t *testing.T) {
+	max := 500
+	z, err := write_blocks(max, timestamped)
+	if err != nil {
+		t.Errorf("failed to write: %s", err)
+		return
+	}
+	for _, n := range []int{0, 1, 2, 77, 250, 498, 499} {
+		for _, delta := range []uint32{0, 1} {
+			// 100+2*n-delta is either the exact timestamp of block n or just before it
+			ts := uint32(100+2*n) - delta
+			calls := 0
+			tsf := func(b []byte) (uint32, error) {
+				calls++
+				return timestampOf(b)
+			}
+			nr := NewSeekableBlockReader(bytes.NewReader(z))
+			err = nr.SeekToTime(ts, tsf)
+			if err != nil {
+				t.Errorf("failed to seek to %d: %s", ts, err)
+				return
+			}
+			got, err := nr.ReadBlock()
+			if err != nil {
+				t.Errorf("failed to read at %d: %s", ts, err)
+				return
+			}
+			expect := timestamped(n)
+			if !issame(got, expect) {
+				t.Errorf("Mismatch: seeking to %d, expected\n\"%s\", but got\n\"%s\"\n", ts, hexstr(expect), hexstr(got))
+			}
+			if calls > 50 {
+				t.Errorf("seeking to %d decoded %d blocks", ts, calls)
+			}
+		}
+	}
+	// after the last block
+	nr := NewSeekableBlockReader(bytes.NewReader(z))
+	err = nr.SeekToTime(uint32(100+2*max), timestampOf)
+	if err != nil {
+		t.Errorf("failed to seek beyond end: %s", err)
+		return
+	}
+	got, err := nr.ReadBlock()
+	if err == nil {
+		t.Errorf("read block after seeking beyond end: \"%s\"", hexstr(got))
+	}
+}
+
+// read a file of tens of thousands of blocks backwards, byte by byte (as prevByte() used to) and through the reverse buffer
+func BenchmarkReadPreviousBlock(b *testing.B) {
+	blocks := 50000
+	z, err := write_blocks(blocks, deterministic1)
+	if err != nil {
+		b.Fatalf("failed to write: %s", err)
+	}
+	fname := filepath.Join(b.TempDir(), "blocks")
+	err = os.WriteFile(fname, z, 0666)
+	if err != nil {
+		b.Fatalf("failed to write file: %s", err)
+	}
+	for _, bufsize := range []int{1, 8192} {
+		b.Run(fmt.Sprintf("bufsize-%d", bufsize), func(b *testing.B) {
+			f, err := os.Open(fname)
+			if err != nil {
+				b.Fatalf("failed to open: %s", err)
+			}
+			defer f.Close()
+			for i := 0; i < b.N; i++ {
+				nr := NewSeekableBlockReader(f)
+				nr.rbuf = make([]byte, bufsize)
+				count := 0
+				_, err := nr.ReadLastBlock()
+				for err == nil {
+					count++
+					_, err = nr.ReadPreviousBlock()
+				}
+				if count != blocks {
+					b.Fatalf("read %d blocks instead of %d", count, blocks)
+				}
+			}
+			b.SetBytes(int64(len(z)))
+		})
+	}
+}
+
+func TestMixedDirections(t *testing.T) {
+	f := deterministic1
+	z, err := write_blocks(50, f)
+	if err != nil {
+		t.Fatalf("failed to write: %s", err)
+	}
+	nr := NewSeekableBlockReader(bytes.NewReader(z))
+	nr.rbuf = make([]byte, 7) // smaller than a block
+	expect := func(n int, got []byte, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("expected block %d, got error %s", n, err)
+		}
+		if !issame(got, f(n)) {
+			t.Fatalf("expected block %d, got \"%s\"", n, hexstr(got))
+		}
+	}
+	got, err := nr.ReadLastBlock()
+	expect(49, got, err)
+	got, err = nr.ReadPreviousBlock()
+	expect(48, got, err)
+	got, err = nr.ReadBlock()
+	expect(48, got, err)
+	got, err = nr.ReadBlock()
+	expect(49, got, err)
+	got, err = nr.ReadPreviousBlock()
+	expect(49, got, err)
+	got, err = nr.ReadPreviousBlock()
+	expect(48, got, err)
+}
+
+func TestChecksum(t *testing.T) {
+	f := deterministic1
+	out := &bytes.Buffer{}
+	nw := NewBlockWriterWithFraming(out, FRAMING_CHECKSUM)
+	var offsets []int
+	for i := 0; i < 10; i++ {
+		offsets = append(offsets, out.Len())
+		nw.Write(f(i))
+	}
+	z := out.Bytes()
+	// flip a bit in the payload of block 4 (the END_BYTE of block 3 precedes its START_BYTE)
+	z[offsets[4]+12] ^= 0x10
+
+	nr := NewSeekableBlockReader(bytes.NewReader(z))
+	for i := 0; i < 10; i++ {
+		got, err := nr.ReadBlock()
+		if i == 4 {
+			var cbe *CorruptBlockError
+			if !errors.As(err, &cbe) {
+				t.Fatalf("expected corrupt block error for block 4, got %v", err)
+			}
+			if cbe.Offset != int64(offsets[4]) {
+				t.Errorf("corrupt block reported at offset %d, expected %d", cbe.Offset, offsets[4])
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("failed to read block %d: %s", i, err)
+		}
+		if !issame(got, f(i)) {
+			t.Errorf("block %d mismatch: \"%s\"", i, hexstr(got))
+		}
+	}
+
+	// same backwards
+	got, err := nr.ReadLastBlock()
+	for i := 9; i >= 0; i-- {
+		if i == 4 {
+			if !IsCorruptBlock(err) {
+				t.Fatalf("expected corrupt block error for block 4 reading backwards, got %v", err)
+			}
+		} else if err != nil {
+			t.Fatalf("failed to read block %d backwards: %s", i, err)
+		} else if !issame(got, f(i)) {
+			t.Errorf("block %d mismatch reading backwards: \"%s\"", i, hexstr(got))
+		}
+		got, err = nr.ReadPreviousBlock()
+	}
+}
+
+func TestMixedFraming(t *testing.T) {
+	f := deterministic1
+	out := &bytes.Buffer{}
+	plain := NewBlockWriter(out)
+	checksummed := NewBlockWriterWithFraming(out, FRAMING_CHECKSUM)
+	for i := 0; i < 20; i++ {
+		if i%3 == 0 {
+			plain.Write(f(i))
+		} else {
+			checksummed.Write(f(i))
+		}
+	}
+	nr := NewBlockReader(bytes.NewReader(out.Bytes()))
+	for i := 0; i < 20; i++ {
+		got, err := nr.ReadBlock()
+		if err != nil {
+			t.Fatalf("failed to read block %d: %s", i, err)
+		}
+		if !issame(got, f(i)) {
+			t.Errorf("block %d mismatch: \"%s\"", i, hexstr(got))
+		}
+	}
+}
@@ -0,0 +1,223 @@
+/*
+a cache for user records. Entries expire after a TTL, the least recently used ones are evicted if the cache is full.
+Unknown users are cached, too (for a shorter time).
+A Get() waits only a short time for a lookup. If the lookup takes longer, Get() returns nil and the lookup completes in the
+background, so that the next Get() for the user finds it in the cache. GetAll() looks up several users concurrently, within
+the same time.
+*/
+package usercache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	apb "golang.conradwood.net/apis/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// how a Get() was answered
+type Result int
+
+const (
+	RESULT_HIT          Result = iota // found in the cache
+	RESULT_NEGATIVE_HIT               // cached as unknown
+	RESULT_MISS                       // looked up
+	RESULT_TIMEOUT                    // looked up, but the lookup did not complete in time
+	RESULT_ERROR                      // looked up, but the lookup failed
+)
+
+func (r Result) String() string {
+	switch r {
+	case RESULT_HIT:
+		return "hit"
+	case RESULT_NEGATIVE_HIT:
+		return "negative_hit"
+	case RESULT_MISS:
+		return "miss"
+	case RESULT_TIMEOUT:
+		return "timeout"
+	case RESULT_ERROR:
+		return "error"
+	}
+	return "unknown"
+}
+
+type Cache struct {
+	Lookup      func(userid string) (*apb.User, error) // looks up a user. an error with code NotFound means the user does not exist
+	TTL         time.Duration                          // how long users are cached. defaults to 10 minutes
+	NegativeTTL time.Duration                          // how long unknown users are cached. defaults to 1 minute
+	MaxEntries  int                                    // defaults to 10000
+	Timeout     time.Duration                          // how long Get() waits for a lookup. defaults to 200ms
+	Observe     func(r Result)                         // optional, called for every Get()
+	lock        sync.Mutex
+	start       sync.Once
+	entries     map[string]*list.Element
+	lru         *list.List // most recently used first
+	inflight    map[string]*call
+	now         func() time.Time
+}
+
+type entry struct {
+	userid  string
+	user    *apb.User // nil if the user does not exist
+	expires time.Time
+}
+
+// a lookup in progress
+type call struct {
+	done chan struct{}
+	user *apb.User
+	err  error
+}
+
+func (c *Cache) init() {
+	c.start.Do(func() {
+		if c.TTL <= 0 {
+			c.TTL = 10 * time.Minute
+		}
+		if c.NegativeTTL <= 0 {
+			c.NegativeTTL = time.Minute
+		}
+		if c.MaxEntries <= 0 {
+			c.MaxEntries = 10000
+		}
+		if c.Timeout <= 0 {
+			c.Timeout = 200 * time.Millisecond
+		}
+		if c.now == nil {
+			c.now = time.Now
+		}
+		c.entries = make(map[string]*list.Element)
+		c.lru = list.New()
+		c.inflight = make(map[string]*call)
+	})
+}
+
+// the user with the given id. nil if it does not exist or could not be looked up in time
+func (c *Cache) Get(userid string) *apb.User {
+	return c.GetAll([]string{userid})[userid]
+}
+
+// the users with the given ids, looked up concurrently. GetAll() waits up to Timeout for all of them together, rather than
+// for each one. Users which do not exist or could not be looked up in time are nil (or missing) in the result
+func (c *Cache) GetAll(userids []string) map[string]*apb.User {
+	c.init()
+	res := make(map[string]*apb.User)
+	var results []Result // of the cache hits
+	var pending []string // looked up, in the order of userids
+	calls := make(map[string]*call)
+	c.lock.Lock()
+	for _, userid := range userids {
+		if _, done := res[userid]; done || calls[userid] != nil {
+			continue
+		}
+		el := c.entries[userid]
+		if el != nil {
+			e := el.Value.(*entry)
+			if c.now().Before(e.expires) {
+				c.lru.MoveToFront(el)
+				res[userid] = e.user
+				if e.user == nil {
+					results = append(results, RESULT_NEGATIVE_HIT)
+				} else {
+					results = append(results, RESULT_HIT)
+				}
+				continue
+			}
+			c.lru.Remove(el)
+			delete(c.entries, userid)
+		}
+		// only one lookup per user at a time
+		cl := c.inflight[userid]
+		if cl == nil {
+			cl = &call{done: make(chan struct{})}
+			c.inflight[userid] = cl
+			go c.lookup(userid, cl)
+		}
+		calls[userid] = cl
+		pending = append(pending, userid)
+	}
+	c.lock.Unlock()
+	for _, r := range results {
+		c.observe(r)
+	}
+	if len(pending) == 0 {
+		return res
+	}
+
+	t := time.NewTimer(c.Timeout)
+	defer t.Stop()
+	timedout := false
+	for _, userid := range pending {
+		cl := calls[userid]
+		if timedout {
+			// only those which completed in the meantime
+			select {
+			case <-cl.done:
+			default:
+				c.observe(RESULT_TIMEOUT)
+				continue
+			}
+		} else {
+			select {
+			case <-cl.done:
+			case <-t.C:
+				timedout = true
+				c.observe(RESULT_TIMEOUT)
+				continue
+			}
+		}
+		if cl.err != nil {
+			c.observe(RESULT_ERROR)
+			continue
+		}
+		c.observe(RESULT_MISS)
+		res[userid] = cl.user
+	}
+	return res
+}
+
+func (c *Cache) lookup(userid string, cl *call) {
+	cl.user, cl.err = c.Lookup(userid)
+	if status.Code(cl.err) == codes.NotFound {
+		cl.user = nil
+		cl.err = nil
+	}
+	c.lock.Lock()
+	delete(c.inflight, userid)
+	if cl.err == nil {
+		// failed lookups are not cached, the next Get() tries again
+		c.add(userid, cl.user)
+	}
+	c.lock.Unlock()
+	close(cl.done)
+}
+
+// must be called with lock held
+func (c *Cache) add(userid string, user *apb.User) {
+	ttl := c.TTL
+	if user == nil {
+		ttl = c.NegativeTTL
+	}
+	e := &entry{userid: userid, user: user, expires: c.now().Add(ttl)}
+	el := c.entries[userid]
+	if el != nil {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[userid] = c.lru.PushFront(e)
+	for c.lru.Len() > c.MaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).userid)
+	}
+}
+
+func (c *Cache) observe(r Result) {
+	if c.Observe != nil {
+		c.Observe(r)
+	}
+}
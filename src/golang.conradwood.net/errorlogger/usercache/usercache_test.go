@@ -0,0 +1,184 @@
+package usercache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	apb "golang.conradwood.net/apis/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeAuth struct {
+	lock    sync.Mutex
+	lookups map[string]int
+	delay   time.Duration
+	down    bool
+}
+
+func (f *fakeAuth) lookup(userid string) (*apb.User, error) {
+	f.lock.Lock()
+	f.lookups[userid]++
+	delay := f.delay
+	down := f.down
+	f.lock.Unlock()
+	time.Sleep(delay)
+	if down {
+		return nil, fmt.Errorf("auth service unavailable")
+	}
+	if userid == "unknown" {
+		return nil, status.Errorf(codes.NotFound, "no such user")
+	}
+	return &apb.User{ID: userid, Email: userid + "@example.com"}, nil
+}
+
+func (f *fakeAuth) count(userid string) int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.lookups[userid]
+}
+
+func newTestCache() (*Cache, *fakeAuth, *time.Time, map[Result]int) {
+	fa := &fakeAuth{lookups: make(map[string]int)}
+	now := time.Now()
+	results := make(map[Result]int)
+	var lock sync.Mutex
+	c := &Cache{
+		Lookup:     fa.lookup,
+		TTL:        time.Minute,
+		MaxEntries: 3,
+		Observe: func(r Result) {
+			lock.Lock()
+			results[r]++
+			lock.Unlock()
+		},
+		now: func() time.Time { return now },
+	}
+	return c, fa, &now, results
+}
+
+func TestCache(t *testing.T) {
+	c, fa, now, results := newTestCache()
+	for i := 0; i < 3; i++ {
+		u := c.Get("1")
+		if u == nil || u.Email != "1@example.com" {
+			t.Fatalf("got wrong user: %v", u)
+		}
+	}
+	if fa.count("1") != 1 || results[RESULT_MISS] != 1 || results[RESULT_HIT] != 2 {
+		t.Errorf("expected 1 lookup, 1 miss and 2 hits, got %d lookups, %v", fa.count("1"), results)
+	}
+
+	// negative caching
+	for i := 0; i < 3; i++ {
+		if c.Get("unknown") != nil {
+			t.Errorf("got a user for an unknown id")
+		}
+	}
+	if fa.count("unknown") != 1 || results[RESULT_NEGATIVE_HIT] != 2 {
+		t.Errorf("expected 1 lookup and 2 negative hits, got %d lookups, %v", fa.count("unknown"), results)
+	}
+	*now = now.Add(2 * time.Minute) // beyond the negative TTL, but not the TTL
+	c.Get("unknown")
+	if fa.count("unknown") != 2 {
+		t.Errorf("unknown user not looked up again after negative TTL")
+	}
+
+	// expiry
+	*now = now.Add(2 * time.Minute)
+	c.Get("1")
+	if fa.count("1") != 2 {
+		t.Errorf("user not looked up again after TTL")
+	}
+
+	// least recently used entries are evicted
+	c.Get("2")
+	c.Get("3")
+	c.Get("1")
+	c.Get("4") // evicts "unknown", then "2"
+	c.Get("5")
+	c.Get("1")
+	c.Get("2")
+	if fa.count("1") != 2 || fa.count("2") != 2 {
+		t.Errorf("wrong entries evicted: %v", fa.lookups)
+	}
+
+	// failed lookups are not cached
+	fa.down = true
+	if c.Get("6") != nil {
+		t.Errorf("got a user although auth is down")
+	}
+	fa.down = false
+	if c.Get("6") == nil {
+		t.Errorf("failed lookup was cached")
+	}
+}
+
+func TestCacheTimeout(t *testing.T) {
+	c, fa, _, results := newTestCache()
+	c.Timeout = 10 * time.Millisecond
+	fa.delay = 200 * time.Millisecond
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c.Get("1") != nil {
+				t.Errorf("got a user although the lookup is slow")
+			}
+		}()
+	}
+	wg.Wait()
+	if results[RESULT_TIMEOUT] != 5 {
+		t.Errorf("expected 5 timeouts, got %v", results)
+	}
+	// the lookup completes in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for c.Get("1") == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("lookup did not complete in the background")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if fa.count("1") != 1 {
+		t.Errorf("expected concurrent lookups to be combined, got %d lookups", fa.count("1"))
+	}
+}
+
+func TestCacheGetAll(t *testing.T) {
+	c, fa, _, results := newTestCache()
+	c.MaxEntries = 10
+	c.Timeout = 500 * time.Millisecond
+	fa.delay = 100 * time.Millisecond
+	c.Get("1")
+	started := time.Now()
+	users := c.GetAll([]string{"1", "2", "3", "2", "unknown", "4", "5"})
+	if time.Since(started) > 400*time.Millisecond {
+		t.Errorf("lookups not concurrent, took %v", time.Since(started))
+	}
+	if len(users) != 6 || users["unknown"] != nil {
+		t.Fatalf("wrong users: %v", users)
+	}
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		if users[id] == nil || users[id].ID != id || fa.count(id) != 1 {
+			t.Errorf("user %s: got %v after %d lookups", id, users[id], fa.count(id))
+		}
+	}
+	if results[RESULT_HIT] != 1 || results[RESULT_MISS] != 6 {
+		t.Errorf("expected 1 hit and 6 misses, got %v", results)
+	}
+
+	// all lookups share one timeout
+	fa.delay = time.Second
+	c.Timeout = 50 * time.Millisecond
+	started = time.Now()
+	users = c.GetAll([]string{"6", "7", "8", "9", "10"})
+	if time.Since(started) > 500*time.Millisecond {
+		t.Errorf("waited for each lookup, took %v", time.Since(started))
+	}
+	if len(users) != 0 || results[RESULT_TIMEOUT] != 5 {
+		t.Errorf("expected 5 timeouts, got users %v, %v", users, results)
+	}
+}
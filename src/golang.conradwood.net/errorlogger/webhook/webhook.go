@@ -0,0 +1,407 @@
+/*
+posts notifications as JSON to webhook URLs.
+Notifications are written to an outbox directory before they are sent, so that they survive a restart. The outbox is
+written to in the background, Send() does not wait for the disk. Each destination
+is delivered to in order by its own worker, with retries and exponential backoff, and is rate limited independently
+*/
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the JSON posted to the webhooks
+type Payload struct {
+	Event       string `json:"event"`          // "alert", "new_group" or "anomaly"
+	Rule        string `json:"rule,omitempty"` // with "alert", the rule which raised it
+	Description string `json:"description,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Service     string `json:"service"`
+	Method      string `json:"method"`
+	Code        uint32 `json:"code"`
+	CodeName    string `json:"code_name"`
+	Message     string `json:"message"` // the LogMessage of a sample error
+	RequestID   string `json:"request_id"`
+	Count       uint64 `json:"count"`
+	Timestamp   uint32 `json:"timestamp"`
+	Team        string `json:"team,omitempty"` // the team owning the service
+	Contact     string `json:"contact,omitempty"`
+	Escalation  string `json:"escalation,omitempty"` // e.g. a chat channel
+}
+
+type Notifier struct {
+	URLs          []string
+	Outbox        string        // directory for notifications which are not delivered yet. if empty they are kept in memory only
+	RatePerMinute float64       // per destination. defaults to 60
+	Burst         int           // notifications which may be sent at once before the rate limit applies. defaults to 10
+	MaxPending    int           // per destination. further notifications are rejected. defaults to 10000
+	MaxAttempts   int           // a notification is given up after this many failed attempts. defaults to 20
+	MinBackoff    time.Duration // wait after the first failed attempt, doubled for each further one. defaults to 1 second
+	MaxBackoff    time.Duration // defaults to 5 minutes
+	Client        *http.Client  // defaults to a client with a 10 second timeout
+	lock          sync.Mutex
+	destinations  map[string]*destination
+	seq           uint64
+	unsaved       []*pending    // queued, but not written to the outbox yet
+	save_wake     chan struct{} // wakes writeOutbox()
+	stop          chan struct{}
+	closed        bool
+	workers       sync.WaitGroup
+}
+
+type destination struct {
+	url     string
+	queue   []*pending // protected by the notifier lock
+	wake    chan struct{}
+	limiter *limiter
+}
+
+// a notification for one destination, as stored in the outbox
+type pending struct {
+	URL      string   `json:"url"`
+	Payload  *Payload `json:"payload"`
+	Attempts int      `json:"attempts"`
+	filename string
+	saved    bool // written to the outbox (or not to be written). protected by the notifier lock
+}
+
+// an error response which will not succeed if retried
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string {
+	return p.err.Error()
+}
+
+// load undelivered notifications from the outbox and start delivering
+func (n *Notifier) Start() error {
+	if n.RatePerMinute <= 0 {
+		n.RatePerMinute = 60
+	}
+	if n.Burst <= 0 {
+		n.Burst = 10
+	}
+	if n.MaxPending <= 0 {
+		n.MaxPending = 10000
+	}
+	if n.MaxAttempts <= 0 {
+		n.MaxAttempts = 20
+	}
+	if n.MinBackoff <= 0 {
+		n.MinBackoff = time.Second
+	}
+	if n.MaxBackoff <= 0 {
+		n.MaxBackoff = 5 * time.Minute
+	}
+	if n.Client == nil {
+		n.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	n.stop = make(chan struct{})
+	n.save_wake = make(chan struct{}, 1)
+	n.destinations = make(map[string]*destination)
+	var urls []string
+	for _, u := range n.URLs {
+		if n.destinations[u] != nil {
+			// notified once only
+			continue
+		}
+		urls = append(urls, u)
+		n.destinations[u] = &destination{
+			url:     u,
+			wake:    make(chan struct{}, 1),
+			limiter: newLimiter(n.RatePerMinute/60, n.Burst),
+		}
+	}
+	n.URLs = urls
+	if n.Outbox != "" {
+		err := os.MkdirAll(n.Outbox, 0700)
+		if err != nil {
+			return err
+		}
+		err = n.loadOutbox()
+		if err != nil {
+			return err
+		}
+		n.workers.Add(1)
+		go n.writeOutbox()
+	}
+	for _, d := range n.destinations {
+		n.workers.Add(1)
+		go n.deliver(d)
+	}
+	return nil
+}
+
+// read notifications left in the outbox by a previous run, oldest first
+func (n *Notifier) loadOutbox() error {
+	names, err := filepath.Glob(filepath.Join(n.Outbox, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	unknown := make(map[string]int)
+	for _, name := range names {
+		bs, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		p := &pending{filename: name, saved: true}
+		err = json.Unmarshal(bs, p)
+		if err != nil || p.Payload == nil {
+			fmt.Printf("Removing broken notification %s: %v\n", name, err)
+			os.Remove(name)
+			continue
+		}
+		d := n.destinations[p.URL]
+		if d == nil {
+			// kept, in case the webhook is configured again
+			unknown[p.URL]++
+			continue
+		}
+		d.queue = append(d.queue, p)
+	}
+	for u, count := range unknown {
+		fmt.Printf("Keeping %d notifications for %s in the outbox, which is not configured\n", count, u)
+	}
+	return nil
+}
+
+// queue a notification to all webhooks
+func (n *Notifier) Send(p *Payload) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.stop == nil || n.closed {
+		return fmt.Errorf("notifier not running")
+	}
+	var errs []string
+	for _, u := range n.URLs {
+		d := n.destinations[u]
+		if len(d.queue) >= n.MaxPending {
+			errs = append(errs, fmt.Sprintf("%s: too many pending notifications", u))
+			continue
+		}
+		pd := &pending{URL: u, Payload: p, saved: n.Outbox == ""}
+		d.queue = append(d.queue, pd)
+		if !pd.saved {
+			// delivered once writeOutbox() saved it
+			n.seq++
+			pd.filename = filepath.Join(n.Outbox, fmt.Sprintf("%019d-%06d.json", time.Now().UnixNano(), n.seq%1000000))
+			n.unsaved = append(n.unsaved, pd)
+			wake(n.save_wake)
+			continue
+		}
+		wake(d.wake)
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("failed to queue notification: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// write queued notifications to the outbox until the notifier is closed
+func (n *Notifier) writeOutbox() {
+	defer n.workers.Done()
+	for {
+		n.lock.Lock()
+		todo := n.unsaved
+		n.unsaved = nil
+		n.lock.Unlock()
+		for _, pd := range todo {
+			err := n.save(pd)
+			n.lock.Lock()
+			if err != nil {
+				fmt.Printf("Failed to write %s, it is lost if not delivered before a restart: %s\n", pd.filename, err)
+				pd.filename = ""
+			}
+			pd.saved = true
+			n.lock.Unlock()
+			wake(n.destinations[pd.URL].wake)
+		}
+		if len(todo) > 0 {
+			continue
+		}
+		select {
+		case <-n.save_wake:
+		case <-n.stop:
+			// Send() does not queue any more, save what it queued before
+			n.lock.Lock()
+			done := len(n.unsaved) == 0
+			n.lock.Unlock()
+			if done {
+				return
+			}
+		}
+	}
+}
+
+// wake up a goroutine waiting on ch, unless it is already woken up
+func wake(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// the number of notifications not delivered yet
+func (n *Notifier) Pending() int {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	res := 0
+	for _, d := range n.destinations {
+		res += len(d.queue)
+	}
+	return res
+}
+
+// stop delivering. undelivered notifications stay in the outbox
+func (n *Notifier) Close() {
+	n.lock.Lock()
+	if n.stop == nil || n.closed {
+		n.lock.Unlock()
+		return
+	}
+	n.closed = true
+	close(n.stop)
+	n.lock.Unlock()
+	n.workers.Wait()
+}
+
+// deliver the notifications of a destination in order
+func (n *Notifier) deliver(d *destination) {
+	defer n.workers.Done()
+	for {
+		var p *pending
+		n.lock.Lock()
+		if len(d.queue) > 0 && d.queue[0].saved {
+			p = d.queue[0]
+		}
+		n.lock.Unlock()
+		if p == nil {
+			select {
+			case <-d.wake:
+				continue
+			case <-n.stop:
+				return
+			}
+		}
+		if !n.sleep(d.limiter.reserve(time.Now())) {
+			return
+		}
+		err := n.post(p)
+		if err == nil {
+			n.remove(d, p)
+			continue
+		}
+		p.Attempts++
+		if _, ok := err.(*permanentError); ok || p.Attempts >= n.MaxAttempts {
+			fmt.Printf("Giving up on notification to %s after %d attempts: %s\n", p.URL, p.Attempts, err)
+			n.remove(d, p)
+			continue
+		}
+		fmt.Printf("Failed to notify %s (attempt %d): %s\n", p.URL, p.Attempts, err)
+		if p.filename != "" {
+			err = n.save(p)
+			if err != nil {
+				fmt.Printf("Failed to update %s: %s\n", p.filename, err)
+			}
+		}
+		if !n.sleep(n.backoff(p.Attempts)) {
+			return
+		}
+	}
+}
+
+// wait for d. false if the notifier was closed meanwhile
+func (n *Notifier) sleep(d time.Duration) bool {
+	if d <= 0 {
+		select {
+		case <-n.stop:
+			return false
+		default:
+			return true
+		}
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-n.stop:
+		return false
+	}
+}
+
+// how long to wait after the given number of failed attempts
+func (n *Notifier) backoff(attempts int) time.Duration {
+	b := float64(n.MinBackoff) * math.Pow(2, float64(attempts-1))
+	if b > float64(n.MaxBackoff) {
+		return n.MaxBackoff
+	}
+	return time.Duration(b)
+}
+
+func (n *Notifier) post(p *pending) error {
+	bs, err := json.Marshal(p.Payload)
+	if err != nil {
+		return &permanentError{err: err}
+	}
+	req, err := http.NewRequest("POST", p.URL, bytes.NewReader(bs))
+	if err != nil {
+		return &permanentError{err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "errorlogger")
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("http status %s", resp.Status)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusRequestTimeout {
+		return &permanentError{err: err}
+	}
+	return err
+}
+
+// write a notification to the outbox
+func (n *Notifier) save(p *pending) error {
+	bs, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	tmpname := p.filename + ".tmp"
+	err = os.WriteFile(tmpname, bs, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpname, p.filename)
+}
+
+// a notification was delivered or given up
+func (n *Notifier) remove(d *destination, p *pending) {
+	n.lock.Lock()
+	d.queue = d.queue[1:]
+	n.lock.Unlock()
+	if p.filename != "" {
+		err := os.Remove(p.filename)
+		if err != nil {
+			fmt.Printf("Failed to remove %s: %s\n", p.filename, err)
+		}
+	}
+}
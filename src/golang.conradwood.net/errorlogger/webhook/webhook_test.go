@@ -0,0 +1,175 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a webhook which records what it received and fails the first "failures" requests with status "status"
+type testHook struct {
+	lock     sync.Mutex
+	received []*Payload
+	times    []time.Time
+	failures int
+	status   int
+}
+
+func (h *testHook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.failures > 0 {
+		h.failures--
+		w.WriteHeader(h.status)
+		return
+	}
+	p := &Payload{}
+	err := json.NewDecoder(r.Body).Decode(p)
+	if err != nil || r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	h.received = append(h.received, p)
+	h.times = append(h.times, time.Now())
+}
+
+func (h *testHook) count() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return len(h.received)
+}
+
+func waitFor(t *testing.T, what string, f func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func newPayload(count uint64) *Payload {
+	return &Payload{Event: "alert", Rule: "r", Service: "foo.Foo", Method: "Get", Code: 13, Message: "failed", RequestID: "req", Count: count}
+}
+
+func TestDelivery(t *testing.T) {
+	h1 := &testHook{failures: 2, status: http.StatusServiceUnavailable}
+	h2 := &testHook{}
+	s1 := httptest.NewServer(h1)
+	defer s1.Close()
+	s2 := httptest.NewServer(h2)
+	defer s2.Close()
+	// configured twice, notified once
+	n := &Notifier{URLs: []string{s1.URL, s2.URL, s1.URL}, MinBackoff: 10 * time.Millisecond}
+	err := n.Start()
+	if err != nil {
+		t.Fatalf("failed to start: %s", err)
+	}
+	defer n.Close()
+	for i := 1; i <= 3; i++ {
+		err = n.Send(newPayload(uint64(i)))
+		if err != nil {
+			t.Fatalf("failed to send: %s", err)
+		}
+	}
+	// retried, in order
+	waitFor(t, "delivery", func() bool { return h1.count() == 3 && h2.count() == 3 })
+	for i, p := range h1.received {
+		if p.Count != uint64(i+1) || p.Service != "foo.Foo" || p.RequestID != "req" {
+			t.Errorf("wrong payload #%d: %v", i, p)
+		}
+	}
+	waitFor(t, "queue to drain", func() bool { return n.Pending() == 0 })
+	if h1.count() != 3 {
+		t.Errorf("expected 3 notifications, got %d", h1.count())
+	}
+
+	// permanent errors are not retried
+	h2.lock.Lock()
+	h2.failures = 1
+	h2.status = http.StatusNotFound
+	h2.lock.Unlock()
+	n.Send(newPayload(4))
+	n.Send(newPayload(5))
+	waitFor(t, "delivery", func() bool { return h2.count() == 4 })
+	if h2.received[3].Count != 5 {
+		t.Errorf("expected notification 4 to be dropped, got %v", h2.received[3])
+	}
+}
+
+func TestOutbox(t *testing.T) {
+	dir := t.TempDir()
+	h := &testHook{failures: 1000, status: http.StatusInternalServerError}
+	s := httptest.NewServer(h)
+	defer s.Close()
+	n := &Notifier{URLs: []string{s.URL}, Outbox: dir, MinBackoff: time.Hour}
+	err := n.Start()
+	if err != nil {
+		t.Fatalf("failed to start: %s", err)
+	}
+	n.Send(newPayload(1))
+	n.Send(newPayload(2))
+	n.Close()
+	if h.count() != 0 {
+		t.Fatalf("expected no delivery")
+	}
+
+	// kept while the webhook is not configured
+	n = &Notifier{URLs: []string{s.URL + "/other"}, Outbox: dir}
+	err = n.Start()
+	if err != nil {
+		t.Fatalf("failed to start: %s", err)
+	}
+	if n.Pending() != 0 {
+		t.Errorf("notifications for another webhook queued")
+	}
+	n.Close()
+
+	// delivered after a restart
+	h.lock.Lock()
+	h.failures = 0
+	h.lock.Unlock()
+	n = &Notifier{URLs: []string{s.URL}, Outbox: dir}
+	err = n.Start()
+	if err != nil {
+		t.Fatalf("failed to start: %s", err)
+	}
+	defer n.Close()
+	waitFor(t, "delivery", func() bool { return h.count() == 2 })
+	if h.received[0].Count != 1 || h.received[1].Count != 2 {
+		t.Errorf("delivered out of order: %v", h.received)
+	}
+	waitFor(t, "outbox to be emptied", func() bool { return n.Pending() == 0 })
+	n.Close()
+	n = &Notifier{URLs: []string{s.URL}, Outbox: dir}
+	n.Start()
+	defer n.Close()
+	if n.Pending() != 0 {
+		t.Errorf("delivered notifications left in the outbox")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	h := &testHook{}
+	s := httptest.NewServer(h)
+	defer s.Close()
+	n := &Notifier{URLs: []string{s.URL}, RatePerMinute: 1200, Burst: 2} // one every 50ms
+	err := n.Start()
+	if err != nil {
+		t.Fatalf("failed to start: %s", err)
+	}
+	defer n.Close()
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		n.Send(newPayload(uint64(i)))
+	}
+	waitFor(t, "delivery", func() bool { return h.count() == 6 })
+	// 2 at once, then 4 more at 50ms intervals
+	if d := h.times[5].Sub(start); d < 190*time.Millisecond {
+		t.Errorf("6 notifications delivered in %v, expected at least 200ms", d)
+	}
+}